@@ -0,0 +1,100 @@
+package Gee
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+// AuthUserKey is the Context key holding the user authenticated by
+// BasicAuth or APIKey.
+const AuthUserKey = "gee.auth.user"
+
+// Accounts maps user names to passwords for BasicAuth.
+type Accounts map[string]string
+
+// AuthUser returns the user stored by BasicAuth or APIKey.
+func (c *Context) AuthUser() string {
+	return c.GetString(AuthUserKey)
+}
+
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm checks HTTP Basic credentials against accounts. Passwords
+// are compared in constant time.
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if len(accounts) == 0 {
+		panic("Gee: BasicAuth needs at least one account")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	hashes := make(map[string][sha256.Size]byte, len(accounts))
+	for user, password := range accounts {
+		if user == "" {
+			panic("Gee: BasicAuth user name must not be empty")
+		}
+		hashes[user] = sha256.Sum256([]byte(password))
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(c *Context) {
+		user, password, ok := c.Rep.BasicAuth()
+		if ok {
+			want, known := hashes[user]
+			got := sha256.Sum256([]byte(password))
+			if subtle.ConstantTimeCompare(want[:], got[:]) == 1 && known {
+				c.Set(AuthUserKey, user)
+				c.Next()
+				return
+			}
+		}
+		c.SetHeader("WWW-Authenticate", challenge)
+		c.Fail(http.StatusUnauthorized, "unauthorized")
+	}
+}
+
+// APIKeyConfig configures the APIKey middleware.
+type APIKeyConfig struct {
+	// Header carries the key; it defaults to X-API-Key.
+	Header string
+	// Query, when set, names a query parameter consulted when the header is
+	// absent.
+	Query string
+	// Lookup returns the user owning key, or false for unknown keys.
+	Lookup func(key string) (user string, ok bool)
+}
+
+// APIKey authenticates requests by the X-API-Key header.
+func APIKey(lookup func(key string) (user string, ok bool)) HandlerFunc {
+	return APIKeyWithConfig(APIKeyConfig{Lookup: lookup})
+}
+
+func APIKeyWithConfig(config APIKeyConfig) HandlerFunc {
+	if config.Lookup == nil {
+		panic("Gee: APIKey needs a lookup function")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	challenge := "APIKey header=" + strconv.Quote(config.Header)
+
+	return func(c *Context) {
+		key := c.Rep.Header.Get(config.Header)
+		if key == "" && config.Query != "" {
+			key = c.Query(config.Query)
+		}
+		if key != "" {
+			if user, ok := config.Lookup(key); ok {
+				c.Set(AuthUserKey, user)
+				c.Next()
+				return
+			}
+		}
+		c.SetHeader("WWW-Authenticate", challenge)
+		c.Fail(http.StatusUnauthorized, "unauthorized")
+	}
+}
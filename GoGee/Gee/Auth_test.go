@@ -0,0 +1,68 @@
+package Gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	engine := New()
+	engine.Use(BasicAuthForRealm(Accounts{"admin": "secret"}, "admin area"))
+	engine.GET("/me", func(c *Context) { c.String(http.StatusOK, "%s", c.AuthUser()) })
+
+	send := func(user, password string, set bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if set {
+			req.SetBasicAuth(user, password)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := send("admin", "secret", true); rec.Code != http.StatusOK || rec.Body.String() != "admin" {
+		t.Fatalf("expected admin, got %d %q", rec.Code, rec.Body.String())
+	}
+	for _, tc := range []struct {
+		user, password string
+		set            bool
+	}{{"admin", "wrong", true}, {"nobody", "secret", true}, {"", "", false}} {
+		rec := send(tc.user, tc.password, tc.set)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Basic realm="admin area", charset="UTF-8"` {
+			t.Fatalf("%+v: expected challenge, got %d %v", tc, rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	keys := map[string]string{"k-123": "billing"}
+	engine := New()
+	engine.Use(APIKeyWithConfig(APIKeyConfig{
+		Query: "api_key",
+		Lookup: func(key string) (string, bool) {
+			user, ok := keys[key]
+			return user, ok
+		},
+	}))
+	engine.GET("/me", func(c *Context) { c.String(http.StatusOK, "%s", c.AuthUser()) })
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("X-API-Key", "k-123")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "billing" {
+		t.Fatalf("expected billing, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me?api_key=k-123", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected query key to be accepted, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me?api_key=nope", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `APIKey header="X-API-Key"` {
+		t.Fatalf("expected challenge, got %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,358 @@
+package Gee
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMultipartMemory = 32 << 20
+
+var (
+	ErrBindNonPointer = errors.New("Gee: bind target must be a non-nil pointer to a struct")
+	ErrMissingBody    = errors.New("Gee: request body is empty")
+)
+
+// Binding decodes a request into obj. Implementations only fill values;
+// validation is applied by Context.ShouldBindWith.
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj interface{}) error
+}
+
+type jsonBinding struct{}
+type xmlBinding struct{}
+type formBinding struct{}
+type multipartBinding struct{}
+type queryBinding struct{}
+type headerBinding struct{}
+
+var (
+	JSONBinding      Binding = jsonBinding{}
+	XMLBinding       Binding = xmlBinding{}
+	FormBinding      Binding = formBinding{}
+	MultipartBinding Binding = multipartBinding{}
+	QueryBinding     Binding = queryBinding{}
+	HeaderBinding    Binding = headerBinding{}
+)
+
+func (jsonBinding) Name() string { return "json" }
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil || req.Body == http.NoBody {
+		return ErrMissingBody
+	}
+	return json.NewDecoder(req.Body).Decode(obj)
+}
+
+func (xmlBinding) Name() string { return "xml" }
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil || req.Body == http.NoBody {
+		return ErrMissingBody
+	}
+	return xml.NewDecoder(req.Body).Decode(obj)
+}
+
+func (formBinding) Name() string { return "form" }
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	return mapForm(obj, "form", true, valuesSource(req.Form), nil)
+}
+
+func (multipartBinding) Name() string { return "multipart/form-data" }
+func (multipartBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		return err
+	}
+	return mapForm(obj, "form", true, valuesSource(req.Form), req.MultipartForm.File)
+}
+
+func (queryBinding) Name() string { return "query" }
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapForm(obj, "query", false, valuesSource(req.URL.Query()), nil)
+}
+
+func (headerBinding) Name() string { return "header" }
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapForm(obj, "header", false, func(key string) ([]string, bool) {
+		values := req.Header.Values(key)
+		return values, len(values) > 0
+	}, nil)
+}
+
+// bindingFor picks the body binding from the request Content-Type. Requests
+// without a recognised body fall back to form binding over the query string.
+func bindingFor(contentType string) Binding {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "application/json":
+		return JSONBinding
+	case "application/xml", "text/xml":
+		return XMLBinding
+	case "multipart/form-data":
+		return MultipartBinding
+	default:
+		return FormBinding
+	}
+}
+
+func valuesSource(values map[string][]string) func(string) ([]string, bool) {
+	return func(key string) ([]string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func paramsSource(params Params) func(string) ([]string, bool) {
+	return func(key string) ([]string, bool) {
+		if value, ok := params.Get(key); ok {
+			return []string{value}, true
+		}
+		return nil, false
+	}
+}
+
+// mapForm fills the fields of obj tagged with tag from source. With fallback
+// set, untagged fields are looked up by their Go name as well.
+func mapForm(obj interface{}, tag string, fallback bool, source func(string) ([]string, bool), files map[string][]*multipart.FileHeader) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ErrBindNonPointer
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return ErrBindNonPointer
+	}
+	return mapStruct(v, tag, fallback, source, files)
+}
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func mapStruct(v reflect.Value, tag string, fallback bool, source func(string) ([]string, bool), files map[string][]*multipart.FileHeader) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if name == "" && isNestedStruct(sf.Type) {
+			if err := mapStruct(fv, tag, fallback, source, files); err != nil {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			if !fallback {
+				continue
+			}
+			name = sf.Name
+		}
+		if sf.Type == fileHeaderType || sf.Type == fileHeaderSliceType {
+			if fhs := files[name]; len(fhs) > 0 {
+				if sf.Type == fileHeaderType {
+					fv.Set(reflect.ValueOf(fhs[0]))
+				} else {
+					fv.Set(reflect.ValueOf(fhs))
+				}
+			}
+			continue
+		}
+		values, ok := source(name)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if err := setField(fv, values); err != nil {
+			return fmt.Errorf("Gee: binding %s %q: %w", tag, name, err)
+		}
+	}
+	return nil
+}
+
+func setField(fv reflect.Value, values []string) error {
+	switch {
+	case fv.Kind() == reflect.Ptr:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8:
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setValue(fv, values[0])
+}
+
+func setValue(fv reflect.Value, value string) error {
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Slice:
+		fv.SetBytes([]byte(value))
+	case reflect.Bool:
+		if value == "" {
+			value = "false"
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			value = "0"
+		}
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// ShouldBindWith decodes the request with b, then fills `uri`, `query` and
+// `header` tagged fields and validates the result.
+func (c *Context) ShouldBindWith(obj interface{}, b Binding) error {
+	if b == MultipartBinding {
+		if _, err := c.MultipartForm(); err != nil {
+			return err
+		}
+	}
+	if err := b.Bind(c.Rep, obj); err != nil {
+		return err
+	}
+	if b != QueryBinding {
+		if err := mapForm(obj, "query", false, valuesSource(c.Rep.URL.Query()), nil); err != nil {
+			return err
+		}
+	}
+	if b != HeaderBinding {
+		if err := HeaderBinding.Bind(c.Rep, obj); err != nil {
+			return err
+		}
+	}
+	if err := mapForm(obj, "uri", false, paramsSource(c.Params), nil); err != nil {
+		return err
+	}
+	return Validator.ValidateStruct(obj)
+}
+
+// ShouldBind picks a binding from the Content-Type header and reports
+// decoding or validation errors without touching the response.
+func (c *Context) ShouldBind(obj interface{}) error {
+	return c.ShouldBindWith(obj, bindingFor(c.Rep.Header.Get("Content-Type")))
+}
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, JSONBinding)
+}
+func (c *Context) ShouldBindXML(obj interface{}) error {
+	return c.ShouldBindWith(obj, XMLBinding)
+}
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return c.ShouldBindWith(obj, QueryBinding)
+}
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return c.ShouldBindWith(obj, HeaderBinding)
+}
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	if err := mapForm(obj, "uri", false, paramsSource(c.Params), nil); err != nil {
+		return err
+	}
+	return Validator.ValidateStruct(obj)
+}
+
+// Bind is ShouldBind that aborts the chain with a 400 JSON response when
+// binding fails. Validation failures list every offending field.
+func (c *Context) Bind(obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		c.failBinding(err)
+		return err
+	}
+	return nil
+}
+func (c *Context) failBinding(err error) {
+	c.Abort()
+	if IsBodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, H{"message": "request body too large"})
+		return
+	}
+	if errors.Is(err, ErrBindingTag) {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, H{"message": "invalid binding rules"})
+		return
+	}
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		c.JSON(http.StatusBadRequest, H{"message": "validation failed", "errors": verrs})
+		return
+	}
+	c.JSON(http.StatusBadRequest, H{"message": err.Error()})
+}
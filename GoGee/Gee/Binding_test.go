@@ -0,0 +1,203 @@
+package Gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type signupForm struct {
+	ID      int      `uri:"id" json:"-"`
+	Name    string   `json:"name" xml:"name" form:"name" binding:"required,min=1,max=8"`
+	Email   string   `json:"email" xml:"email" form:"email" binding:"required,email"`
+	Role    string   `json:"role" xml:"role" form:"role" binding:"omitempty,oneof=admin user"`
+	Tags    []string `json:"tags" xml:"tag" form:"tag"`
+	Page    int      `query:"page"`
+	TraceID string   `header:"X-Trace-ID"`
+}
+
+func serveBind(t *testing.T, req *http.Request) (*httptest.ResponseRecorder, *signupForm, error) {
+	t.Helper()
+	engine := New()
+	var form signupForm
+	var bindErr error
+	engine.POST("/users/:id", func(c *Context) {
+		if bindErr = c.Bind(&form); bindErr != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec, &form, bindErr
+}
+
+func assertBoundForm(t *testing.T, form *signupForm) {
+	t.Helper()
+	if form.ID != 7 || form.Name != "tom" || form.Email != "tom@example.com" || form.Role != "admin" {
+		t.Fatalf("unexpected bound form %+v", form)
+	}
+	if len(form.Tags) != 2 || form.Tags[1] != "b" {
+		t.Fatalf("unexpected tags %v", form.Tags)
+	}
+	if form.Page != 3 || form.TraceID != "trace-1" {
+		t.Fatalf("expected query and header fields, got %+v", form)
+	}
+}
+
+func TestBindSources(t *testing.T) {
+	jsonReq := httptest.NewRequest(http.MethodPost, "/users/7?page=3",
+		strings.NewReader(`{"name":"tom","email":"tom@example.com","role":"admin","tags":["a","b"]}`))
+	jsonReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	xmlReq := httptest.NewRequest(http.MethodPost, "/users/7?page=3",
+		strings.NewReader(`<signup><name>tom</name><email>tom@example.com</email><role>admin</role><tag>a</tag><tag>b</tag></signup>`))
+	xmlReq.Header.Set("Content-Type", "application/xml")
+
+	values := url.Values{"name": {"tom"}, "email": {"tom@example.com"}, "role": {"admin"}, "tag": {"a", "b"}}
+	formReq := httptest.NewRequest(http.MethodPost, "/users/7?page=3", strings.NewReader(values.Encode()))
+	formReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for key, vs := range values {
+		for _, v := range vs {
+			_ = mw.WriteField(key, v)
+		}
+	}
+	_ = mw.Close()
+	multipartReq := httptest.NewRequest(http.MethodPost, "/users/7?page=3", &body)
+	multipartReq.Header.Set("Content-Type", mw.FormDataContentType())
+
+	for _, req := range []*http.Request{jsonReq, xmlReq, formReq, multipartReq} {
+		req.Header.Set("X-Trace-ID", "trace-1")
+		rec, form, err := serveBind(t, req)
+		if err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("%s: bind failed status=%d err=%v body=%s", req.Header.Get("Content-Type"), rec.Code, err, rec.Body.String())
+		}
+		assertBoundForm(t, form)
+	}
+}
+
+func TestBindValidationErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/7",
+		strings.NewReader(`{"name":"a-very-long-name","email":"not-an-email","role":"root"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec, _, err := serveBind(t, req)
+
+	verrs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %T %v", err, err)
+	}
+	rules := make(map[string]string)
+	for _, fe := range verrs {
+		rules[fe.Field] = fe.Rule
+	}
+	if rules["name"] != "max" || rules["email"] != "email" || rules["role"] != "oneof" {
+		t.Fatalf("unexpected field errors %+v", verrs)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var resp struct {
+		Message string       `json:"message"`
+		Errors  []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Errors) != 3 {
+		t.Fatalf("unexpected error body %s", rec.Body.String())
+	}
+}
+
+func TestBindRequiredAndMalformed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/users/7", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	_, _, err := serveBind(t, req)
+	verrs, ok := err.(ValidationErrors)
+	if !ok || len(verrs) != 2 || verrs[0].Rule != "required" {
+		t.Fatalf("expected two required errors, got %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users/7", strings.NewReader(`{"name":`))
+	req.Header.Set("Content-Type", "application/json")
+	rec, _, err := serveBind(t, req)
+	if err == nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected malformed json to abort with 400, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users/abc", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	if _, _, err := serveBind(t, req); err == nil || !strings.Contains(err.Error(), "uri") {
+		t.Fatalf("expected uri conversion error, got %v", err)
+	}
+}
+
+func TestValidatorNestedAndNumbers(t *testing.T) {
+	type address struct {
+		City string `json:"city" binding:"required"`
+	}
+	type order struct {
+		Count   int      `json:"count" binding:"min=1,max=10"`
+		Items   []string `json:"items" binding:"required,max=2"`
+		Address address  `json:"address"`
+	}
+	err := Validator.ValidateStruct(&order{Count: 11, Items: []string{"a", "b", "c"}})
+	verrs, ok := err.(ValidationErrors)
+	if !ok || len(verrs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", err)
+	}
+	if verrs[2].Field != "address.city" {
+		t.Fatalf("expected nested field name, got %s", verrs[2].Field)
+	}
+	if err := Validator.ValidateStruct(&order{Count: 2, Items: []string{"a"}, Address: address{City: "x"}}); err != nil {
+		t.Fatalf("expected valid order, got %v", err)
+	}
+}
+
+func TestValidatorZeroValuesAndOmitEmpty(t *testing.T) {
+	type filter struct {
+		Page  int    `query:"page" binding:"min=1"`
+		Sort  string `query:"sort" binding:"oneof=asc desc"`
+		Code  string `query:"code" binding:"len=3"`
+		Email string `query:"email" binding:"omitempty,email"`
+		Limit *int   `query:"limit" binding:"max=100"`
+	}
+	verrs, ok := Validator.ValidateStruct(&filter{}).(ValidationErrors)
+	if !ok || len(verrs) != 3 || verrs[0].Rule != "min" || verrs[1].Rule != "oneof" || verrs[2].Rule != "len" {
+		t.Fatalf("expected min, oneof and len to reject zero values, got %v", verrs)
+	}
+	if err := Validator.ValidateStruct(&filter{Page: 1, Sort: "asc", Code: "abc"}); err != nil {
+		t.Fatalf("expected omitempty and nil pointers to pass, got %v", err)
+	}
+}
+
+func TestValidatorRejectsBadTags(t *testing.T) {
+	type typo struct {
+		Name string `json:"name" binding:"requried"`
+	}
+	type badLimit struct {
+		Name string `json:"name" binding:"max=ten"`
+	}
+	for _, obj := range []interface{}{&typo{}, &badLimit{}} {
+		if err := Validator.ValidateStruct(obj); !errors.Is(err, ErrBindingTag) {
+			t.Fatalf("expected ErrBindingTag for %T even with zero input, got %v", obj, err)
+		}
+	}
+
+	engine := New()
+	engine.POST("/", func(c *Context) {
+		_ = c.Bind(&typo{})
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"tom"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for a malformed tag, got %d", rec.Code)
+	}
+}
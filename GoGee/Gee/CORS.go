@@ -0,0 +1,149 @@
+package Gee
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowOrigins holds exact origins such as "https://app.example.com",
+	// wildcard subdomains such as "https://*.example.com", or "*". "*" cannot
+	// be combined with AllowCredentials.
+	AllowOrigins []string
+	// AllowOriginFunc is consulted when no entry of AllowOrigins matches.
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods restricts the methods announced in preflight responses.
+	// When empty, every method routed for the path is announced.
+	AllowMethods []string
+	// AllowHeaders lists request headers clients may send. When empty, the
+	// headers asked for in the preflight are echoed back.
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type corsOrigins struct {
+	any       bool
+	exact     map[string]bool
+	wildcards [][2]string
+	fn        func(string) bool
+}
+
+func (o *corsOrigins) allowed(origin string) bool {
+	if o.any {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if o.exact[lower] {
+		return true
+	}
+	for _, w := range o.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			if !strings.ContainsAny(lower[len(w[0]):len(lower)-len(w[1])], "/:") {
+				return true
+			}
+		}
+	}
+	return o.fn != nil && o.fn(origin)
+}
+
+// CORS answers cross-origin requests according to config. Install it with
+// Use on the engine or a group: preflight OPTIONS requests are answered even
+// when no OPTIONS route exists, announcing the methods routed for the path.
+func CORS(config CORSConfig) HandlerFunc {
+	origins := &corsOrigins{exact: make(map[string]bool), fn: config.AllowOriginFunc}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			origins.any = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			origins.wildcards = append(origins.wildcards, [2]string{prefix, suffix})
+		default:
+			origins.exact[origin] = true
+		}
+	}
+	if origins.any && config.AllowCredentials {
+		panic("Gee: CORS cannot allow credentials for every origin")
+	}
+	// A bare "*" yields the same headers for every origin; anything else
+	// depends on the Origin header.
+	varyOrigin := !origins.any
+	allowMethods := make(map[string]bool)
+	for _, method := range config.AllowMethods {
+		allowMethods[strings.ToUpper(method)] = true
+	}
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := ""
+	if config.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+
+	return func(c *Context) {
+		header := c.Writer.Header()
+		if varyOrigin {
+			header.Add("Vary", "Origin")
+		}
+		origin := c.Rep.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		preflight := c.Method == http.MethodOptions && c.Rep.Header.Get("Access-Control-Request-Method") != ""
+		if !origins.allowed(origin) {
+			if preflight {
+				c.Abort()
+				c.Status(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if origins.any {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		methods := make([]string, 0)
+		for _, method := range c.engine.servingRouter(c.Rep.Host, c.Path).allowedMethods(c.Path) {
+			if len(allowMethods) == 0 || allowMethods[method] {
+				methods = append(methods, method)
+			}
+		}
+		if len(methods) == 0 {
+			c.Next()
+			return
+		}
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Rep.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.Abort()
+		c.Status(http.StatusNoContent)
+	}
+}
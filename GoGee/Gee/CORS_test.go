@@ -0,0 +1,140 @@
+package Gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSEngine(config CORSConfig) *Engine {
+	engine := New()
+	api := engine.Group("/api")
+	api.Use(CORS(config))
+	api.GET("/items", func(c *Context) { c.String(http.StatusOK, "items") })
+	api.POST("/items", func(c *Context) { c.String(http.StatusCreated, "created") })
+	engine.GET("/public", func(c *Context) { c.String(http.StatusOK, "public") })
+	return engine
+}
+
+func corsRequest(engine *Engine, method string, path string, origin string, preflightMethod string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflightMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", preflightMethod)
+		req.Header.Set("Access-Control-Request-Headers", "X-Token")
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflightWithoutOptionsRoute(t *testing.T) {
+	engine := newCORSEngine(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	rec := corsRequest(engine, http.MethodOptions, "/api/items", "https://app.example.com", http.MethodPost)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 preflight, got %d", rec.Code)
+	}
+	h := rec.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("unexpected preflight headers %v", h)
+	}
+	if h.Get("Access-Control-Allow-Methods") != "GET, POST" || h.Get("Access-Control-Allow-Headers") != "X-Token" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("unexpected preflight negotiation %v", h)
+	}
+	if vary := h.Values("Vary"); len(vary) != 3 || vary[0] != "Origin" {
+		t.Fatalf("unexpected Vary %v", vary)
+	}
+
+	rec = corsRequest(engine, http.MethodOptions, "/api/items", "https://evil.example", http.MethodPost)
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected rejected preflight, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = corsRequest(engine, http.MethodOptions, "/api/items", "", "")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("plain OPTIONS should still be 405, got %d", rec.Code)
+	}
+}
+
+func TestCORSSimpleRequests(t *testing.T) {
+	engine := newCORSEngine(CORSConfig{
+		AllowOrigins:    []string{"https://*.example.com"},
+		AllowOriginFunc: func(origin string) bool { return origin == "http://localhost:3000" },
+		ExposeHeaders:   []string{"X-Request-ID"},
+	})
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://evil.com/.example.com", false},
+		{"http://localhost:3000", true},
+	}
+	for _, tc := range cases {
+		rec := corsRequest(engine, http.MethodGet, "/api/items", tc.origin, "")
+		got := rec.Header().Get("Access-Control-Allow-Origin")
+		if tc.allowed && (got != tc.origin || rec.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID") {
+			t.Fatalf("%s: expected allowed, got headers %v", tc.origin, rec.Header())
+		}
+		if !tc.allowed && got != "" {
+			t.Fatalf("%s: expected no CORS headers, got %s", tc.origin, got)
+		}
+		if rec.Body.String() != "items" || rec.Header().Get("Vary") != "Origin" {
+			t.Fatalf("%s: handler must still run with Vary: Origin, got %q %v", tc.origin, rec.Body.String(), rec.Header())
+		}
+	}
+
+	rec := corsRequest(engine, http.MethodGet, "/public", "https://app.example.com", "")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("routes outside the CORS group must not get CORS headers")
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	engine := newCORSEngine(CORSConfig{AllowOrigins: []string{"*"}, AllowMethods: []string{"GET"}})
+	rec := corsRequest(engine, http.MethodGet, "/api/items", "https://any.site", "")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Vary") != "" {
+		t.Fatalf("unexpected wildcard headers %v", rec.Header())
+	}
+	rec = corsRequest(engine, http.MethodOptions, "/api/items", "https://any.site", http.MethodGet)
+	if rec.Header().Get("Access-Control-Allow-Methods") != "GET" {
+		t.Fatalf("expected methods filtered by AllowMethods, got %v", rec.Header())
+	}
+}
+
+func TestCORSPreflightFallsBackToDefaultHost(t *testing.T) {
+	engine := New()
+	engine.Use(CORS(CORSConfig{AllowOrigins: []string{"https://app.example.com"}}))
+	engine.POST("/items", func(c *Context) { c.String(http.StatusCreated, "created") })
+	engine.Host("api.example.com").GET("/status", func(c *Context) { c.String(http.StatusOK, "up") })
+
+	req := httptest.NewRequest(http.MethodOptions, "http://api.example.com/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") != "POST" {
+		t.Fatalf("expected the default host's methods in the preflight, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCORSRejectsCredentialsForAnyOrigin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for \"*\" with AllowCredentials")
+		}
+	}()
+	CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
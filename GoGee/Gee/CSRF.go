@@ -0,0 +1,252 @@
+package Gee
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrCSRFOrigin = errors.New("Gee: cross-origin request rejected")
+	ErrCSRFToken  = errors.New("Gee: CSRF token missing or invalid")
+)
+
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit keeps the token in a cookie that the request must
+	// echo in a header or form field. It needs no server-side state.
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer keeps the token in the session, so it needs the
+	// Sessions middleware in front of CSRF.
+	CSRFSynchronizer
+)
+
+const (
+	csrfTokenLength   = 32
+	csrfSessionKey    = "gee.csrf"
+	csrfDefaultCookie = "_gee_csrf"
+)
+
+// CSRFConfig configures the CSRF middleware.
+type CSRFConfig struct {
+	Mode CSRFMode
+	// Cookie configures the double-submit cookie; Name defaults to
+	// "_gee_csrf". Set ScriptAccess for scripts that read the token from
+	// the cookie.
+	Cookie SessionOptions
+	// Header and FormField carry the submitted token. They default to
+	// "X-CSRF-Token" and "_csrf".
+	Header    string
+	FormField string
+	// TrustedOrigins lists other origins, such as "https://admin.example.com",
+	// allowed to send unsafe requests.
+	TrustedOrigins []string
+	// Skip exempts requests from the check. Mounting CSRF only on the groups
+	// serving pages is usually simpler.
+	Skip func(*Context) bool
+	// OnRejected writes the rejection; it defaults to a 403 JSON response.
+	OnRejected func(c *Context, err error)
+}
+
+type csrfConfig struct {
+	CSRFConfig
+	trusted map[string]bool
+}
+
+// CSRF rejects unsafe requests that come from another origin or lack the
+// token handed out by c.CSRFToken. GET, HEAD, OPTIONS and TRACE pass
+// unchecked, so they must not change state. Templates rendered with
+// c.HTMLTemplate get the token from the csrfToken function.
+func CSRF(config CSRFConfig) HandlerFunc {
+	if config.Mode != CSRFDoubleSubmit && config.Mode != CSRFSynchronizer {
+		panic("Gee: unknown CSRF mode")
+	}
+	if config.Cookie.Name == "" {
+		config.Cookie.Name = csrfDefaultCookie
+	}
+	config.Cookie = config.Cookie.withDefaults()
+	if config.Header == "" {
+		config.Header = "X-CSRF-Token"
+	}
+	if config.FormField == "" {
+		config.FormField = "_csrf"
+	}
+	if config.OnRejected == nil {
+		config.OnRejected = func(c *Context, err error) {
+			c.Fail(http.StatusForbidden, "CSRF check failed")
+		}
+	}
+	cc := &csrfConfig{CSRFConfig: config, trusted: make(map[string]bool, len(config.TrustedOrigins))}
+	for _, origin := range config.TrustedOrigins {
+		cc.trusted[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+
+	return func(c *Context) {
+		c.csrf = cc
+		if isSafeMethod(c.Method) || (cc.Skip != nil && cc.Skip(c)) {
+			c.Next()
+			return
+		}
+		err := cc.checkOrigin(c)
+		if err == nil {
+			err = cc.checkToken(c)
+		}
+		if err != nil {
+			c.Abort()
+			c.Error(err)
+			cc.OnRejected(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// checkOrigin compares Origin, or Referer when Origin is missing, with the
+// request host. HTTPS requests carrying neither are rejected, as browsers
+// always send one of them there.
+func (cc *csrfConfig) checkOrigin(c *Context) error {
+	source := c.Rep.Header.Get("Origin")
+	if source == "" {
+		source = c.Rep.Header.Get("Referer")
+	}
+	if source == "" {
+		if c.Rep.TLS != nil {
+			return ErrCSRFOrigin
+		}
+		return nil
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return ErrCSRFOrigin
+	}
+	if strings.EqualFold(u.Host, c.Rep.Host) && (c.Rep.TLS == nil || u.Scheme == "https") {
+		return nil
+	}
+	if cc.trusted[strings.ToLower(u.Scheme+"://"+u.Host)] {
+		return nil
+	}
+	return ErrCSRFOrigin
+}
+
+func (cc *csrfConfig) checkToken(c *Context) error {
+	submitted := c.Rep.Header.Get(cc.Header)
+	if submitted == "" {
+		if isMultipart(c.Rep) {
+			_, _ = c.MultipartForm()
+		}
+		submitted = c.Rep.PostFormValue(cc.FormField)
+	}
+	token := cc.load(c)
+	if token == nil || submitted == "" {
+		return ErrCSRFToken
+	}
+	if subtle.ConstantTimeCompare(unmaskCSRFToken(submitted), token) != 1 {
+		return ErrCSRFToken
+	}
+	return nil
+}
+
+// load returns the token already issued to the client, or nil.
+func (cc *csrfConfig) load(c *Context) []byte {
+	if c.csrfToken != nil {
+		return c.csrfToken
+	}
+	var encoded string
+	if cc.Mode == CSRFSynchronizer {
+		encoded, _ = c.Session().Get(csrfSessionKey).(string)
+	} else {
+		encoded, _ = c.Cookie(cc.Cookie.Name)
+	}
+	token, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(token) != csrfTokenLength {
+		return nil
+	}
+	c.csrfToken = token
+	return token
+}
+
+// issue creates a token and hands it to the client. It must run before the
+// response headers are sent.
+func (cc *csrfConfig) issue(c *Context) ([]byte, error) {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(token)
+	if cc.Mode == CSRFSynchronizer {
+		s := c.Session()
+		s.Set(csrfSessionKey, encoded)
+		if err := s.Save(); err != nil {
+			return nil, err
+		}
+	} else {
+		if c.Writer.Written() {
+			return nil, errors.New("Gee: CSRF token issued after the response headers were sent")
+		}
+		c.SetCookie(cc.Cookie.cookie(encoded, false))
+	}
+	c.csrfToken = token
+	return token, nil
+}
+
+// CSRFToken returns the token to embed in forms or send in the CSRF header,
+// issuing one on first use. The value is masked afresh on every call so it
+// cannot be recovered from compressed responses; any of them is accepted.
+func (c *Context) CSRFToken() string {
+	if c.csrf == nil {
+		panic("Gee: c.CSRFToken needs the CSRF middleware")
+	}
+	token := c.csrf.load(c)
+	if token == nil {
+		var err error
+		if token, err = c.csrf.issue(c); err != nil {
+			c.Error(err)
+			return ""
+		}
+	}
+	return maskCSRFToken(token)
+}
+
+func maskCSRFToken(token []byte) string {
+	masked := make([]byte, 2*len(token))
+	pad := masked[:len(token)]
+	if _, err := rand.Read(pad); err != nil {
+		return base64.RawURLEncoding.EncodeToString(token)
+	}
+	for i := range token {
+		masked[len(token)+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// unmaskCSRFToken accepts masked tokens and the raw cookie value.
+func unmaskCSRFToken(submitted string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil {
+		return nil
+	}
+	switch len(data) {
+	case csrfTokenLength:
+		return data
+	case 2 * csrfTokenLength:
+		token := data[csrfTokenLength:]
+		for i := range token {
+			token[i] ^= data[i]
+		}
+		return token
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package Gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func csrfRequest(engine *Engine, method, target string, body url.Values, header http.Header, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body != nil {
+		req = httptest.NewRequest(method, target, strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	dir := t.TempDir()
+	form := `<input name="_csrf" value="{{csrfToken}}">`
+	if err := os.WriteFile(filepath.Join(dir, "form.tmpl"), []byte(form), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := New()
+	engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	pages := engine.Group("/pages")
+	pages.Use(CSRF(CSRFConfig{TrustedOrigins: []string{"https://admin.example.com/"}}))
+	pages.GET("/form", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "form.tmpl", nil)
+	})
+	pages.POST("/form", func(c *Context) {
+		c.String(http.StatusOK, "saved")
+	})
+	engine.POST("/api/items", func(c *Context) {
+		c.String(http.StatusOK, "created")
+	})
+	engine.GET("/plain", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "form.tmpl", nil)
+	})
+
+	rec := csrfRequest(engine, http.MethodGet, "/pages/form", nil, nil)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != "_gee_csrf" {
+		t.Fatalf("expected a CSRF cookie, got %d %v", rec.Code, rec.Header())
+	}
+	cookie := cookies[0]
+	token := strings.TrimSuffix(strings.TrimPrefix(rec.Body.String(), `<input name="_csrf" value="`), `">`)
+	if token == "" || token == cookie.Value {
+		t.Fatalf("expected a masked token, got %q", rec.Body.String())
+	}
+	if rec := csrfRequest(engine, http.MethodGet, "/pages/form", nil, nil, cookie); rec.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("existing token must be reused, got %q", rec.Header().Get("Set-Cookie"))
+	}
+
+	cases := []struct {
+		name    string
+		body    url.Values
+		header  http.Header
+		cookies []*http.Cookie
+		code    int
+	}{
+		{"form field", url.Values{"_csrf": {token}}, nil, []*http.Cookie{cookie}, http.StatusOK},
+		{"raw cookie in header", nil, http.Header{"X-Csrf-Token": {cookie.Value}}, []*http.Cookie{cookie}, http.StatusOK},
+		{"trusted origin", url.Values{"_csrf": {token}}, http.Header{"Origin": {"https://admin.example.com"}}, []*http.Cookie{cookie}, http.StatusOK},
+		{"same origin referer", url.Values{"_csrf": {token}}, http.Header{"Referer": {"http://example.com/pages/form"}}, []*http.Cookie{cookie}, http.StatusOK},
+		{"missing token", url.Values{}, nil, []*http.Cookie{cookie}, http.StatusForbidden},
+		{"missing cookie", url.Values{"_csrf": {token}}, nil, nil, http.StatusForbidden},
+		{"wrong token", url.Values{"_csrf": {maskCSRFToken(make([]byte, csrfTokenLength))}}, nil, []*http.Cookie{cookie}, http.StatusForbidden},
+		{"foreign origin", url.Values{"_csrf": {token}}, http.Header{"Origin": {"https://evil.example"}}, []*http.Cookie{cookie}, http.StatusForbidden},
+		{"null origin", url.Values{"_csrf": {token}}, http.Header{"Origin": {"null"}}, []*http.Cookie{cookie}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		if rec := csrfRequest(engine, http.MethodPost, "/pages/form", tc.body, tc.header, tc.cookies...); rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d %s", tc.name, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := csrfRequest(engine, http.MethodPost, "/api/items", nil, nil); rec.Code != http.StatusOK {
+		t.Fatalf("routes outside the CSRF group must not be checked, got %d", rec.Code)
+	}
+	if rec := csrfRequest(engine, http.MethodGet, "/plain", nil, nil); rec.Body.String() != `<input name="_csrf" value="">` {
+		t.Fatalf("csrfToken must render empty without the middleware, got %q", rec.Body.String())
+	}
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	engine := New()
+	engine.Use(Sessions(NewMemoryStore(DefaultSessionOptions)))
+	var rejected error
+	engine.Use(CSRF(CSRFConfig{
+		Mode: CSRFSynchronizer,
+		OnRejected: func(c *Context, err error) {
+			rejected = err
+			c.String(http.StatusTeapot, "rejected")
+		},
+	}))
+	engine.GET("/token", func(c *Context) {
+		c.String(http.StatusOK, "%s", c.CSRFToken())
+	})
+	engine.DELETE("/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "deleted %s", c.Param("id"))
+	})
+
+	login := func() (*http.Cookie, string) {
+		rec := csrfRequest(engine, http.MethodGet, "/token", nil, nil)
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "gee_session" {
+			t.Fatalf("expected only a session cookie, got %v", rec.Header())
+		}
+		return cookies[0], rec.Body.String()
+	}
+	session, token := login()
+	other, otherToken := login()
+
+	if rec := csrfRequest(engine, http.MethodDelete, "/items/1", nil, http.Header{"X-Csrf-Token": {token}}, session); rec.Body.String() != "deleted 1" {
+		t.Fatalf("expected the delete to pass, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := csrfRequest(engine, http.MethodDelete, "/items/1", nil, http.Header{"X-Csrf-Token": {otherToken}}, session); rec.Code != http.StatusTeapot || rejected != ErrCSRFToken {
+		t.Fatalf("a token of another session must be rejected, got %d %v", rec.Code, rejected)
+	}
+	if rec := csrfRequest(engine, http.MethodDelete, "/items/1", nil, http.Header{"X-Csrf-Token": {otherToken}}, other); rec.Code != http.StatusOK {
+		t.Fatalf("expected the other session to pass, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodDelete, "https://example.com/items/1", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	req.AddCookie(session)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTeapot || rejected != ErrCSRFOrigin {
+		t.Fatalf("HTTPS requests without Origin or Referer must be rejected, got %d %v", rec.Code, rejected)
+	}
+}
@@ -0,0 +1,369 @@
+package Gee
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressConfig controls the Compress middleware.
+type CompressConfig struct {
+	// Level is a compress/flate level; zero means gzip.DefaultCompression.
+	Level int
+	// MinLength is the smallest body, in bytes, worth compressing.
+	MinLength int
+	// ContentTypes lists compressible media types; a trailing "/*" matches
+	// a whole family such as "text/*".
+	ContentTypes []string
+	// ExcludedPaths are path prefixes that are never compressed.
+	ExcludedPaths []string
+	// ExcludedExtensions are file extensions, with the dot, that are never
+	// compressed.
+	ExcludedExtensions []string
+	// DecompressRequest inflates request bodies sent with
+	// Content-Encoding: gzip before handlers read them.
+	DecompressRequest bool
+	// MaxDecompressedSize caps an inflated request body; reading past it
+	// fails like BodyLimit and Bind answers 413. Zero means
+	// DefaultMaxDecompressedSize, a negative value removes the cap.
+	MaxDecompressedSize int64
+}
+
+const DefaultMaxDecompressedSize = 32 << 20
+
+var DefaultCompressConfig = CompressConfig{
+	Level:     gzip.DefaultCompression,
+	MinLength: 1024,
+	ContentTypes: []string{
+		"text/*",
+		"application/json",
+		"application/problem+json",
+		"application/javascript",
+		"application/xml",
+		"image/svg+xml",
+	},
+	ExcludedExtensions: []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".gz", ".br", ".zip", ".woff2"},
+}
+
+type encoderPool struct {
+	name string
+	pool sync.Pool
+}
+
+type resettableWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func Compress() HandlerFunc {
+	return CompressWithConfig(DefaultCompressConfig)
+}
+
+// CompressWithConfig compresses responses with gzip or deflate according to
+// Accept-Encoding, using pooled encoders.
+func CompressWithConfig(config CompressConfig) HandlerFunc {
+	level := config.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		panic(fmt.Sprintf("Gee: invalid compression level %d", level))
+	}
+	gzipPool := &encoderPool{name: "gzip"}
+	gzipPool.pool.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	deflatePool := &encoderPool{name: "deflate"}
+	deflatePool.pool.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, level)
+		return w
+	}
+
+	maxDecompressed := config.MaxDecompressedSize
+	if maxDecompressed == 0 {
+		maxDecompressed = DefaultMaxDecompressedSize
+	}
+
+	return func(c *Context) {
+		if config.DecompressRequest && !decompressRequest(c, maxDecompressed) {
+			return
+		}
+		if compressExcluded(c.Path, config) {
+			c.Next()
+			return
+		}
+		// Without a pool the response is only inspected to decide on Vary.
+		var pool *encoderPool
+		if c.Method != http.MethodHead {
+			switch negotiateEncoding(c.Rep.Header.Get("Accept-Encoding")) {
+			case "gzip":
+				pool = gzipPool
+			case "deflate":
+				pool = deflatePool
+			}
+		}
+
+		original := c.Writer
+		cw := &compressWriter{ResponseWriter: original, config: &config, pool: pool, status: http.StatusOK}
+		c.Writer = cw
+		defer func() {
+			cw.finish()
+			c.Writer = original
+		}()
+		c.Next()
+	}
+}
+
+func decompressRequest(c *Context, limit int64) bool {
+	if !strings.EqualFold(c.Rep.Header.Get("Content-Encoding"), "gzip") || c.Rep.Body == nil {
+		return true
+	}
+	zr, err := gzip.NewReader(c.Rep.Body)
+	if err != nil {
+		c.Fail(http.StatusBadRequest, "invalid gzip request body")
+		return false
+	}
+	var body io.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{zr, c.Rep.Body}
+	if limit > 0 {
+		body = http.MaxBytesReader(c.Writer, body, limit)
+	}
+	c.Rep.Body = body
+	c.Rep.Header.Del("Content-Encoding")
+	c.Rep.Header.Del("Content-Length")
+	c.Rep.ContentLength = -1
+	return true
+}
+
+func compressExcluded(p string, config CompressConfig) bool {
+	for _, prefix := range config.ExcludedPaths {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	ext := strings.ToLower(path.Ext(p))
+	for _, excluded := range config.ExcludedExtensions {
+		if ext != "" && ext == strings.ToLower(excluded) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// honouring q-values and preferring gzip on ties.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	wildcard := -1.0
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		seen[name] = true
+		switch name {
+		case "*":
+			wildcard = q
+		case "gzip", "deflate":
+			if q > bestQ || (q == bestQ && name == "gzip") {
+				best, bestQ = name, q
+			}
+		}
+	}
+	if best == "" && wildcard > 0 && !seen["gzip"] {
+		return "gzip"
+	}
+	return best
+}
+
+func (config *CompressConfig) allowsType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range config.ContentTypes {
+		if family, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, family+"/") {
+				return true
+			}
+			continue
+		}
+		if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the start of the body until it knows whether the
+// response qualifies for compression, then streams through the encoder.
+type compressWriter struct {
+	ResponseWriter
+	config *CompressConfig
+	pool   *encoderPool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	encoder     resettableWriter
+	buf         []byte
+	size        int
+}
+
+// WriteHeader records code until the headers are sent. Until body bytes
+// are accepted, a later call replaces it, as Recovery needs.
+func (w *compressWriter) WriteHeader(code int) {
+	if informational(code) {
+		if !w.decided {
+			w.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	if w.decided || w.wroteHeader && len(w.buf) > 0 {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.size += len(data)
+	if w.decided {
+		return w.writeThrough(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.config.MinLength {
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+func (w *compressWriter) writeThrough(data []byte) (int, error) {
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// decide commits the headers, enabling the encoder when the response is
+// large enough, not already encoded and of an allowed content type. A
+// streaming response being flushed skips the size threshold.
+func (w *compressWriter) decide(streaming bool) error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	// Ranges address the identity body, so partial responses stay as they
+	// are.
+	compressible := header.Get("Content-Encoding") == "" &&
+		w.status != http.StatusPartialContent && header.Get("Content-Range") == "" &&
+		(streaming || len(w.buf) >= w.config.MinLength) &&
+		w.status >= http.StatusOK && w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+		w.config.allowsType(header.Get("Content-Type"))
+	if compressible {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if compressible && w.pool != nil {
+		header.Set("Content-Encoding", w.pool.name)
+		header.Del("Content-Length")
+		// The encoded body is no longer byte-identical to the one a strong
+		// ETag names.
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.encoder = w.pool.pool.Get().(resettableWriter)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.writeThrough(buf)
+	return err
+}
+
+// finish flushes whatever is buffered and returns the encoder to its pool.
+func (w *compressWriter) finish() {
+	if !w.decided && (w.wroteHeader || len(w.buf) > 0) {
+		_ = w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.pool.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = w.decide(false)
+}
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = w.decide(true)
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+func (w *compressWriter) Status() int {
+	if w.decided {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+func (w *compressWriter) Size() int {
+	if !w.wroteHeader {
+		return noWritten
+	}
+	return w.size
+}
+
+// Written reports whether the headers were sent or body bytes accepted; a
+// status alone can still be replaced.
+func (w *compressWriter) Written() bool {
+	return w.ResponseWriter.Written() || len(w.buf) > 0
+}
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,215 @@
+package Gee
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newCompressEngine(config CompressConfig) *Engine {
+	engine := New()
+	engine.Use(CompressWithConfig(config))
+	large := strings.Repeat(`{"id":1,"name":"gee"},`, 100)
+	engine.GET("/list", func(c *Context) {
+		c.SetHeader("Content-Type", "application/json")
+		c.String(http.StatusOK, "[%s]", large)
+	})
+	engine.GET("/small", func(c *Context) {
+		c.String(http.StatusOK, "tiny")
+	})
+	engine.GET("/image.png", func(c *Context) {
+		c.Data(http.StatusOK, []byte(large))
+	})
+	engine.GET("/encoded", func(c *Context) {
+		c.SetHeader("Content-Encoding", "br")
+		c.SetHeader("Content-Type", "text/plain")
+		c.String(http.StatusOK, "%s", large)
+	})
+	engine.GET("/partial", func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain")
+		c.SetHeader("Content-Range", fmt.Sprintf("bytes 0-%d/5000", len(large)-1))
+		c.String(http.StatusPartialContent, "%s", large)
+	})
+	engine.GET("/tagged", func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain")
+		c.SetHeader("ETag", `"v1"`)
+		c.String(http.StatusOK, "%s", large)
+	})
+	engine.GET("/stream", func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain")
+		_, _ = c.Writer.Write([]byte("first "))
+		c.Writer.Flush()
+		_, _ = c.Writer.Write([]byte("second"))
+	})
+	engine.POST("/echo", func(c *Context) {
+		body, _ := io.ReadAll(c.Rep.Body)
+		c.String(http.StatusOK, "%s", body)
+	})
+	return engine
+}
+
+func doCompressRequest(engine *Engine, method string, path string, encoding string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	if encoding != "" {
+		req.Header.Set("Accept-Encoding", encoding)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressGzipAndDeflate(t *testing.T) {
+	engine := newCompressEngine(DefaultCompressConfig)
+
+	rec := doCompressRequest(engine, http.MethodGet, "/list", "gzip, deflate", nil)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected gzip response with Vary, got %v", rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	plain, _ := io.ReadAll(zr)
+	if !strings.HasPrefix(string(plain), `[{"id":1`) || len(plain) != 2202 {
+		t.Fatalf("unexpected decompressed body length %d", len(plain))
+	}
+
+	rec = doCompressRequest(engine, http.MethodGet, "/list", "gzip;q=0.5, deflate", nil)
+	if rec.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected deflate by q-value, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	fr, err := zlib.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid deflate body: %v", err)
+	}
+	if plain, _ = io.ReadAll(fr); len(plain) != 2202 {
+		t.Fatalf("unexpected deflate body length %d", len(plain))
+	}
+}
+
+func TestCompressSkips(t *testing.T) {
+	engine := newCompressEngine(DefaultCompressConfig)
+	cases := []struct {
+		path     string
+		encoding string
+		want     string
+	}{
+		{"/list", "", ""},
+		{"/list", "identity", ""},
+		{"/small", "gzip", ""},
+		{"/image.png", "gzip", ""},
+		{"/encoded", "gzip", "br"},
+		{"/partial", "gzip", ""},
+	}
+	for _, tc := range cases {
+		rec := doCompressRequest(engine, http.MethodGet, tc.path, tc.encoding, nil)
+		if got := rec.Header().Get("Content-Encoding"); got != tc.want {
+			t.Fatalf("%s with %q: expected encoding %q, got %q", tc.path, tc.encoding, tc.want, got)
+		}
+		if rec.Code/100 != 2 || rec.Body.Len() == 0 {
+			t.Fatalf("%s: unexpected response %d", tc.path, rec.Code)
+		}
+	}
+	if rec := doCompressRequest(engine, http.MethodGet, "/small", "gzip", nil); rec.Body.String() != "tiny" {
+		t.Fatalf("small body should pass through, got %q", rec.Body.String())
+	}
+	for _, path := range []string{"/small", "/image.png", "/encoded"} {
+		if vary := doCompressRequest(engine, http.MethodGet, path, "gzip", nil).Header().Get("Vary"); vary != "" {
+			t.Fatalf("%s can never be compressed, expected no Vary, got %q", path, vary)
+		}
+	}
+	if vary := doCompressRequest(engine, http.MethodGet, "/list", "", nil).Header().Get("Vary"); vary != "Accept-Encoding" {
+		t.Fatalf("identity responses that could be compressed need Vary, got %q", vary)
+	}
+}
+
+func TestCompressStatusNotCommittedBeforeFlush(t *testing.T) {
+	engine := New()
+	engine.Use(Compress(), Recovery())
+	engine.GET("/panic", func(c *Context) {
+		c.Status(http.StatusOK)
+		panic("kaboom")
+	})
+	rec := doCompressRequest(engine, http.MethodGet, "/panic", "", nil)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "internal server error") {
+		t.Fatalf("expected Recovery to replace the buffered status, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCompressWeakensETag(t *testing.T) {
+	engine := newCompressEngine(DefaultCompressConfig)
+	if rec := doCompressRequest(engine, http.MethodGet, "/tagged", "gzip", nil); rec.Header().Get("ETag") != `W/"v1"` {
+		t.Fatalf("expected weak ETag on compressed body, got %q", rec.Header().Get("ETag"))
+	}
+	if rec := doCompressRequest(engine, http.MethodGet, "/tagged", "", nil); rec.Header().Get("ETag") != `"v1"` {
+		t.Fatalf("expected strong ETag on identity body, got %q", rec.Header().Get("ETag"))
+	}
+}
+
+func TestCompressFlushStreams(t *testing.T) {
+	engine := newCompressEngine(DefaultCompressConfig)
+	rec := doCompressRequest(engine, http.MethodGet, "/stream", "gzip", nil)
+	if !rec.Flushed || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected flushed gzip stream, got %v", rec.Header())
+	}
+	zr, _ := gzip.NewReader(rec.Body)
+	if plain, _ := io.ReadAll(zr); string(plain) != "first second" {
+		t.Fatalf("unexpected stream body %q", plain)
+	}
+}
+
+func TestCompressDecompressRequest(t *testing.T) {
+	config := DefaultCompressConfig
+	config.DecompressRequest = true
+	engine := newCompressEngine(config)
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, _ = zw.Write([]byte("hello gzip"))
+	_ = zw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/echo", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Body.String() != "hello gzip" {
+		t.Fatalf("expected inflated request body, got %q", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for corrupt gzip body, got %d", rec.Code)
+	}
+}
+
+func TestCompressDecompressedSizeLimit(t *testing.T) {
+	engine := New()
+	engine.Use(CompressWithConfig(CompressConfig{DecompressRequest: true, MaxDecompressedSize: 1 << 10}))
+	engine.POST("/bind", func(c *Context) {
+		var payload map[string]string
+		if c.Bind(&payload) == nil {
+			c.String(http.StatusOK, "ok")
+		}
+	})
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, _ = zw.Write([]byte(`{"pad":"` + strings.Repeat("a", 1<<20) + `"}`))
+	_ = zw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/bind", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized inflated body, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package Gee
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// builtinConstraints are the named constraints available in every pattern,
+// as in "/users/:id<int>".
+var builtinConstraints = map[string]func(string) bool{
+	"int":  isIntParam,
+	"uuid": isUUIDParam,
+}
+
+// RegisterConstraint makes name usable as a param constraint, as in
+// "/posts/:slug<slug>", for routes registered afterwards. Constraints that
+// are not registered names are regular expressions a whole segment must
+// match; they cannot contain '/'.
+func (engine *Engine) RegisterConstraint(name string, match func(string) bool) {
+	if name == "" || match == nil {
+		panic("Gee: constraint needs a name and a matcher")
+	}
+	if _, ok := builtinConstraints[name]; ok {
+		panic(fmt.Sprintf("Gee: constraint %q is already registered", name))
+	}
+	if _, ok := engine.constraints[name]; ok {
+		panic(fmt.Sprintf("Gee: constraint %q is already registered", name))
+	}
+	engine.constraints[name] = match
+}
+
+// splitParam splits a wildcard such as "id<int>", without its ':' or '*',
+// into its name and constraint.
+func splitParam(part string) (string, string) {
+	i := strings.IndexByte(part, '<')
+	if i < 0 || part[len(part)-1] != '>' {
+		return part, ""
+	}
+	return part[:i], part[i+1 : len(part)-1]
+}
+
+// resolveConstraint returns the matcher of a registered constraint, or
+// compiles constraint as an anchored regular expression.
+func resolveConstraint(constraint string, registered map[string]func(string) bool) (func(string) bool, error) {
+	if match, ok := builtinConstraints[constraint]; ok {
+		return match, nil
+	}
+	if match, ok := registered[constraint]; ok {
+		return match, nil
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// isIntParam accepts what strconv.Atoi accepts on 64-bit platforms,
+// without allocating for rejected values.
+func isIntParam(s string) bool {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || len(digits) > 19 {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+	if len(digits) == 19 {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+	}
+	return true
+}
+
+func isUUIDParam(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// UUID is a parsed RFC 4122 UUID.
+type UUID [16]byte
+
+var ErrInvalidUUID = errors.New("Gee: invalid UUID")
+
+// ParseUUID parses the canonical 8-4-4-4-12 hexadecimal form.
+func ParseUUID(s string) (UUID, error) {
+	var id UUID
+	if !isUUIDParam(s) {
+		return id, ErrInvalidUUID
+	}
+	hexDigits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(id[:], []byte(hexDigits)); err != nil {
+		return id, ErrInvalidUUID
+	}
+	return id, nil
+}
+
+func (id UUID) String() string {
+	s := hex.EncodeToString(id[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// ParamInt returns the named path param as an int.
+func (c *Context) ParamInt(key string) (int, error) {
+	value, ok := c.Params.Get(key)
+	if !ok {
+		return 0, fmt.Errorf("Gee: no path param %q", key)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Gee: path param %q: %w", key, err)
+	}
+	return n, nil
+}
+
+// ParamUUID returns the named path param as a UUID.
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	value, ok := c.Params.Get(key)
+	if !ok {
+		return UUID{}, fmt.Errorf("Gee: no path param %q", key)
+	}
+	id, err := ParseUUID(value)
+	if err != nil {
+		return UUID{}, fmt.Errorf("Gee: path param %q: %w", key, err)
+	}
+	return id, nil
+}
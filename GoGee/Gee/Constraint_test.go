@@ -0,0 +1,110 @@
+package Gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouteConstraints(t *testing.T) {
+	engine := New()
+	engine.RegisterConstraint("even", func(s string) bool {
+		return isIntParam(s) && (s[len(s)-1]-'0')%2 == 0
+	})
+	engine.GET("/users/:id<int>", func(c *Context) {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			t.Errorf("ParamInt: %v", err)
+		}
+		c.String(http.StatusOK, "user %d", id+1)
+	}).Name("user")
+	engine.GET("/users/:uuid<uuid>", func(c *Context) {
+		id, err := c.ParamUUID("uuid")
+		if err != nil {
+			t.Errorf("ParamUUID: %v", err)
+		}
+		c.String(http.StatusOK, "account %s", id)
+	})
+	engine.GET("/users/:name", func(c *Context) {
+		c.String(http.StatusOK, "named %s", c.Param("name"))
+	})
+	engine.GET("/posts/:slug<[a-z0-9-]+>/comments", func(c *Context) {
+		c.String(http.StatusOK, "comments of %s", c.Param("slug"))
+	})
+	engine.GET("/pages/:n<even>", func(c *Context) {
+		c.String(http.StatusOK, "even page %s", c.Param("n"))
+	})
+	engine.NoRoute(func(c *Context) {
+		c.String(http.StatusNotFound, "no route")
+	})
+
+	cases := []struct {
+		path, body string
+	}{
+		{"/users/41", "user 42"},
+		{"/users/-1", "user 0"},
+		{"/users/99999999999999999999", "named 99999999999999999999"},
+		{"/users/1F3A0C2E-55B1-4C7A-9D3E-0A1B2C3D4E5F", "account 1f3a0c2e-55b1-4c7a-9d3e-0a1b2c3d4e5f"},
+		{"/users/tom", "named tom"},
+		{"/posts/hello-world-2/comments", "comments of hello-world-2"},
+		{"/posts/Hello/comments", "no route"},
+		{"/pages/4", "even page 4"},
+		{"/pages/3", "no route"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Body.String() != tc.body {
+			t.Errorf("%s: got %d %q", tc.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	routes := engine.Routes()
+	if routes[0].Pattern != "/users/:id<int>" || !reflect.DeepEqual(routes[0].Constraints, map[string]string{"id": "int"}) || routes[2].Constraints != nil {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	data, _ := json.Marshal(routes[3])
+	if !strings.Contains(string(data), `"constraints":{"slug":"[a-z0-9-]+"}`) {
+		t.Fatalf("unexpected route JSON %s", data)
+	}
+	if u, err := engine.URL("user", "id", 7); err != nil || u != "/users/7" {
+		t.Fatalf("unexpected URL %q %v", u, err)
+	}
+
+	params := engine.OpenAPI(OpenAPIInfo{})["paths"].(H)["/users/{id}"].(H)["get"].(H)["parameters"].([]H)
+	if !reflect.DeepEqual(params[0]["schema"], H{"type": "integer", "format": "int64"}) {
+		t.Fatalf("unexpected OpenAPI param %v", params[0])
+	}
+}
+
+func TestRouteConstraintErrors(t *testing.T) {
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected a panic", name)
+			}
+		}()
+		fn()
+	}
+	handler := func(c *Context) {}
+	mustPanic("bad regexp", func() { New().GET("/a/:x<[a->", handler) })
+	mustPanic("catch-all constraint", func() { New().GET("/a/*x<int>", handler) })
+	mustPanic("same constraint twice", func() {
+		engine := New()
+		engine.GET("/a/:x<int>", handler)
+		engine.GET("/a/:y<int>", handler)
+	})
+	mustPanic("built-in name", func() { New().RegisterConstraint("int", isIntParam) })
+
+	c := &Context{Params: Params{{Key: "id", Value: "x"}}}
+	if _, err := c.ParamInt("id"); err == nil {
+		t.Fatal("expected an error for a non-numeric param")
+	}
+	if _, err := c.ParamUUID("missing"); err == nil {
+		t.Fatal("expected an error for a missing param")
+	}
+}
@@ -4,40 +4,121 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html/template"
+	"log/slog"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type H map[string]interface{}
 
+// Context is pooled by Engine and reset between requests, so it must not be
+// retained or used from other goroutines after the handler chain returns.
+//
+// Context implements context.Context: cancellation comes from the request,
+// and Value falls back to Keys for string keys, so c can be handed to
+// libraries expecting a context.Context.
 type Context struct {
-	Writer     http.ResponseWriter
+	Writer     ResponseWriter
 	Rep        *http.Request
 	Method     string
 	Path       string
-	Params     map[string]string
+	Params     Params
 	Keys       map[string]interface{}
 	engine     *Engine
 	handles    []HandlerFunc
 	index      int
 	StatusCode int
+	writer     responseWriter
+	fullPath   string
+	errs       []error
+
+	sessionStore SessionStore
+	session      *Session
+	csrf         *csrfConfig
+	csrfToken    []byte
+	// multipartForm is removed after the request, since c.Rep may be a
+	// copy the server does not know about.
+	multipartForm *multipart.Form
+
+	// Set by LoggerWithConfig.
+	baseLogger   *slog.Logger
+	logger       *slog.Logger
+	timing       bool
+	handlerStart time.Time
+	handlerEnd   time.Time
 }
 
 func (c *Context) Param(key string) string {
-	return c.Params[key]
+	return c.Params.ByName(key)
 }
 func NewContext(w http.ResponseWriter, req *http.Request, engine *Engine) *Context {
-	return &Context{
-		Writer:  w,
-		Method:  req.Method,
-		Path:    req.URL.Path,
-		Rep:     req,
-		engine:  engine,
-		Keys:    make(map[string]interface{}),
-		handles: make([]HandlerFunc, 0),
-		index:   -1,
+	c := &Context{engine: engine}
+	c.Reset(w, req)
+	return c
+}
+
+// Reset prepares c for serving req, keeping the Keys map and Params slice
+// allocated by earlier requests.
+func (c *Context) Reset(w http.ResponseWriter, req *http.Request) {
+	c.writer.reset(w)
+	c.Writer = &c.writer
+	c.Rep = req
+	c.Method = req.Method
+	c.Path = req.URL.Path
+	c.Params = c.Params[:0]
+	clear(c.Keys)
+	c.handles = nil
+	c.index = -1
+	c.StatusCode = 0
+	c.fullPath = ""
+	clear(c.errs)
+	c.errs = c.errs[:0]
+	c.sessionStore = nil
+	c.session = nil
+	c.csrf = nil
+	c.csrfToken = nil
+	c.multipartForm = nil
+	c.baseLogger = nil
+	c.logger = nil
+	c.timing = false
+	c.handlerStart = time.Time{}
+	c.handlerEnd = time.Time{}
+}
+
+// FullPath returns the pattern of the matched route, such as "/users/:id",
+// or "" when no route matched.
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For and X-Real-IP
+// are only trusted when Engine.ForwardedByClientIP is set.
+func (c *Context) ClientIP() string {
+	if c.engine != nil && c.engine.ForwardedByClientIP {
+		if forwarded := c.Rep.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(c.Rep.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
 	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Rep.RemoteAddr))
+	if err != nil {
+		return c.Rep.RemoteAddr
+	}
+	return host
 }
 func (c *Context) Set(key string, value interface{}) {
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
 	c.Keys[key] = value
 }
 func (c *Context) Get(key string) (interface{}, bool) {
@@ -53,7 +134,9 @@ func (c *Context) GetString(key string) string {
 	return ""
 }
 func (c *Context) PostForm(key string) string {
-	//fmt.Println(key)
+	if isMultipart(c.Rep) {
+		_, _ = c.MultipartForm()
+	}
 	return c.Rep.FormValue(key)
 }
 func (c *Context) Query(key string) string {
@@ -103,12 +186,17 @@ func (c *Context) HTML(code int, html string) {
 	c.Writer.Write([]byte(html))
 }
 func (c *Context) HTMLTemplate(code int, name string, data interface{}) {
-	if c.engine == nil || c.engine.htmlTemplates == nil {
+	if c.engine == nil || c.engine.htmlRender == nil {
 		http.Error(c.Writer, "html templates not configured", http.StatusInternalServerError)
 		return
 	}
+	var funcs template.FuncMap
+	if c.csrf != nil {
+		funcs = template.FuncMap{"csrfToken": c.CSRFToken}
+	}
 	var buf bytes.Buffer
-	if err := c.engine.htmlTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+	if err := c.engine.htmlRender.Render(&buf, name, data, funcs); err != nil {
+		c.Error(err)
 		http.Error(c.Writer, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -116,6 +204,67 @@ func (c *Context) HTMLTemplate(code int, name string, data interface{}) {
 	c.Status(code)
 	_, _ = c.Writer.Write(buf.Bytes())
 }
+
+func (c *Context) Deadline() (time.Time, bool) {
+	if c.Rep == nil {
+		return time.Time{}, false
+	}
+	return c.Rep.Context().Deadline()
+}
+func (c *Context) Done() <-chan struct{} {
+	if c.Rep == nil {
+		return nil
+	}
+	return c.Rep.Context().Done()
+}
+func (c *Context) Err() error {
+	if c.Rep == nil {
+		return nil
+	}
+	return c.Rep.Context().Err()
+}
+func (c *Context) Value(key interface{}) interface{} {
+	if c.Rep != nil {
+		if value := c.Rep.Context().Value(key); value != nil {
+			return value
+		}
+	}
+	if name, ok := key.(string); ok {
+		if value, ok := c.Keys[name]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// Error records err for the access log without touching the response.
+func (c *Context) Error(err error) {
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+}
+func (c *Context) Errors() []error {
+	return c.errs
+}
+
+// Logger returns a logger for the current request, carrying the request ID
+// set by RequestID. It is the logger of LoggerWithConfig when one is
+// installed and slog.Default() otherwise.
+func (c *Context) Logger() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	logger := c.baseLogger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	requestID := c.GetString("request_id")
+	if requestID == "" {
+		return logger
+	}
+	c.logger = logger.With(slog.String("request_id", requestID))
+	return c.logger
+}
 func (c *Context) Abort() {
 	c.index = len(c.handles)
 }
@@ -127,6 +276,12 @@ func (c *Context) Next() {
 	c.index++
 	handlersLen := len(c.handles)
 	for ; c.index < handlersLen; c.index++ {
+		if c.timing && c.index == handlersLen-1 {
+			c.handlerStart = time.Now()
+			c.handles[c.index](c)
+			c.handlerEnd = time.Now()
+			continue
+		}
 		c.handles[c.index](c)
 	}
 }
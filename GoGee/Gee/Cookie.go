@@ -0,0 +1,178 @@
+package Gee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrInvalidCookie = errors.New("Gee: invalid cookie value")
+	ErrCookieExpired = errors.New("Gee: cookie value is expired")
+)
+
+// Cookie returns the unescaped value of the named request cookie.
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Rep.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return cookie.Value, nil
+	}
+	return value, nil
+}
+
+// SetCookie adds a Set-Cookie header. Path defaults to "/" and the value is
+// query-escaped; SameSite, Secure and HttpOnly are sent as given.
+func (c *Context) SetCookie(cookie *http.Cookie) {
+	cc := *cookie
+	if cc.Path == "" {
+		cc.Path = "/"
+	}
+	cc.Value = url.QueryEscape(cc.Value)
+	http.SetCookie(c.Writer, &cc)
+}
+
+// DeleteCookie tells the client to drop the named cookie.
+func (c *Context) DeleteCookie(name string, path string) {
+	c.SetCookie(&http.Cookie{Name: name, Path: path, MaxAge: -1, Expires: time.Unix(0, 0)})
+}
+
+// CookieCodec protects cookie values. Encode uses the first key, Decode
+// accepts any key, so keys are rotated by prepending a new one and
+// dropping the oldest once its cookies have expired.
+type CookieCodec interface {
+	Encode(name string, value []byte) (string, error)
+	Decode(name string, encoded string) ([]byte, error)
+}
+
+// SignedCookieCodec authenticates values with HMAC-SHA256. Values stay
+// readable by the client.
+type SignedCookieCodec struct {
+	keys [][]byte
+	// MaxAge rejects values signed longer ago; zero disables the check.
+	MaxAge time.Duration
+}
+
+// NewSignedCookieCodec takes HMAC keys of at least 32 bytes.
+func NewSignedCookieCodec(keys ...[]byte) (*SignedCookieCodec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("Gee: signed cookie codec needs a key")
+	}
+	for _, key := range keys {
+		if len(key) < 32 {
+			return nil, errors.New("Gee: signed cookie keys must be at least 32 bytes")
+		}
+	}
+	return &SignedCookieCodec{keys: keys}, nil
+}
+
+func cookieMAC(key []byte, name string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// timestamped prefixes value with the current Unix time.
+func timestamped(value []byte) []byte {
+	payload := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	return append(payload, value...)
+}
+
+func checkTimestamp(payload []byte, maxAge time.Duration) ([]byte, error) {
+	if len(payload) < 8 {
+		return nil, ErrInvalidCookie
+	}
+	issued := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if maxAge > 0 && time.Since(issued) > maxAge {
+		return nil, ErrCookieExpired
+	}
+	return payload[8:], nil
+}
+
+func (codec *SignedCookieCodec) Encode(name string, value []byte) (string, error) {
+	payload := timestamped(value)
+	signed := append(payload, cookieMAC(codec.keys[0], name, payload)...)
+	return base64.RawURLEncoding.EncodeToString(signed), nil
+}
+func (codec *SignedCookieCodec) Decode(name string, encoded string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < sha256.Size {
+		return nil, ErrInvalidCookie
+	}
+	payload, sum := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	for _, key := range codec.keys {
+		if hmac.Equal(sum, cookieMAC(key, name, payload)) {
+			return checkTimestamp(payload, codec.MaxAge)
+		}
+	}
+	return nil, ErrInvalidCookie
+}
+
+// EncryptedCookieCodec encrypts and authenticates values with AES-GCM, the
+// cookie name being bound as additional data.
+type EncryptedCookieCodec struct {
+	aeads []cipher.AEAD
+	// MaxAge rejects values encrypted longer ago; zero disables the check.
+	MaxAge time.Duration
+}
+
+// NewEncryptedCookieCodec takes AES keys of 16, 24 or 32 bytes.
+func NewEncryptedCookieCodec(keys ...[]byte) (*EncryptedCookieCodec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("Gee: encrypted cookie codec needs a key")
+	}
+	codec := &EncryptedCookieCodec{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("Gee: encrypted cookie key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		codec.aeads = append(codec.aeads, aead)
+	}
+	return codec, nil
+}
+
+func (codec *EncryptedCookieCodec) Encode(name string, value []byte) (string, error) {
+	aead := codec.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+8+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, timestamped(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+func (codec *EncryptedCookieCodec) Decode(name string, encoded string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	for _, aead := range codec.aeads {
+		if len(data) < aead.NonceSize() {
+			break
+		}
+		payload, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+		if err == nil {
+			return checkTimestamp(payload, codec.MaxAge)
+		}
+	}
+	return nil, ErrInvalidCookie
+}
@@ -0,0 +1,114 @@
+package Gee
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextCookies(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {
+		theme, err := c.Cookie("theme")
+		if err != nil || theme != "dark mode" {
+			t.Errorf("unexpected cookie %q %v", theme, err)
+		}
+		if _, err := c.Cookie("missing"); !errors.Is(err, http.ErrNoCookie) {
+			t.Errorf("expected ErrNoCookie, got %v", err)
+		}
+		c.SetCookie(&http.Cookie{Name: "lang", Value: "zh cn", MaxAge: 60, Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		c.DeleteCookie("theme", "")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark+mode"})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	cookies := rec.Header().Values("Set-Cookie")
+	if len(cookies) != 2 || cookies[0] != "lang=zh+cn; Path=/; Max-Age=60; HttpOnly; Secure; SameSite=Strict" {
+		t.Fatalf("unexpected Set-Cookie %q", cookies)
+	}
+	if !strings.HasPrefix(cookies[1], "theme=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0") {
+		t.Fatalf("unexpected deletion %q", cookies[1])
+	}
+}
+
+func TestCookieCodecsRotateKeys(t *testing.T) {
+	oldSigning, newSigning := bytes.Repeat([]byte("o"), 32), bytes.Repeat([]byte("n"), 32)
+	oldAES, newAES := bytes.Repeat([]byte("a"), 32), bytes.Repeat([]byte("b"), 16)
+	oldSigned, err := NewSignedCookieCodec(oldSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotatedSigned, err := NewSignedCookieCodec(newSigning, oldSigning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSignedCookieCodec([]byte("short")); err == nil {
+		t.Fatal("expected an error for a short signing key")
+	}
+	oldEncrypted, err := NewEncryptedCookieCodec(oldAES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotatedEncrypted, err := NewEncryptedCookieCodec(newAES, oldAES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name         string
+		old, rotated CookieCodec
+		readable     bool
+	}{
+		{"signed", oldSigned, rotatedSigned, true},
+		{"encrypted", oldEncrypted, rotatedEncrypted, false},
+	}
+	for _, tc := range cases {
+		encoded, err := tc.old.Encode("session", []byte("user=alice"))
+		if err != nil {
+			t.Fatalf("%s: encode failed: %v", tc.name, err)
+		}
+		if value, err := tc.rotated.Decode("session", encoded); err != nil || string(value) != "user=alice" {
+			t.Fatalf("%s: rotated codec must read old values, got %q %v", tc.name, value, err)
+		}
+		if _, err := tc.rotated.Decode("other", encoded); !errors.Is(err, ErrInvalidCookie) {
+			t.Fatalf("%s: value must be bound to the cookie name, got %v", tc.name, err)
+		}
+		tampered := []byte(encoded)
+		tampered[len(tampered)/2] ^= 1
+		if _, err := tc.rotated.Decode("session", string(tampered)); !errors.Is(err, ErrInvalidCookie) {
+			t.Fatalf("%s: tampered value accepted: %v", tc.name, err)
+		}
+		fresh, _ := tc.rotated.Encode("session", []byte("user=alice"))
+		if _, err := tc.old.Decode("session", fresh); !errors.Is(err, ErrInvalidCookie) {
+			t.Fatalf("%s: old codec must not know the new key, got %v", tc.name, err)
+		}
+		if readable := strings.Contains(string(mustDecodeBase64(t, fresh)), "user=alice"); readable != tc.readable {
+			t.Fatalf("%s: readable=%v", tc.name, readable)
+		}
+	}
+
+	old := make([]byte, 8)
+	binary.BigEndian.PutUint64(old, uint64(time.Now().Add(-2*time.Hour).Unix()))
+	if _, err := checkTimestamp(old, time.Hour); !errors.Is(err, ErrCookieExpired) {
+		t.Fatalf("expected expired value, got %v", err)
+	}
+	if _, err := checkTimestamp(old, 0); err != nil {
+		t.Fatalf("zero MaxAge must disable the check, got %v", err)
+	}
+}
+
+func mustDecodeBase64(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -109,3 +109,96 @@ func TestContextHTMLTemplate(t *testing.T) {
 		t.Fatalf("unexpected template body: %s", rec.Body.String())
 	}
 }
+
+func TestRouteHandlerChain(t *testing.T) {
+	engine := New()
+	var order []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			order = append(order, name)
+			c.Next()
+		}
+	}
+	engine.Use(mark("root"))
+	api := engine.Group("/api")
+	api.Use(mark("api"))
+	v1 := api.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/ping", mark("route"), func(c *Context) {
+		order = append(order, "handler")
+		c.String(http.StatusOK, "pong")
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+	engine.ServeHTTP(rec, req)
+
+	expected := []string{"root", "api", "v1", "route", "handler"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected chain %v, got %v", expected, order)
+	}
+}
+
+func TestGroupMiddlewareMatchesSegments(t *testing.T) {
+	engine := New()
+	hits := 0
+	v2 := engine.Group("/v2")
+	v2.Use(func(c *Context) {
+		hits++
+		c.Next()
+	})
+	v2.GET("/ping", func(c *Context) { c.String(http.StatusOK, "v2") })
+	engine.GET("/v2x/ping", func(c *Context) { c.String(http.StatusOK, "v2x") })
+
+	for _, path := range []string{"/v2/ping", "/v2x/ping"} {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		engine.ServeHTTP(rec, req)
+	}
+	if hits != 1 {
+		t.Fatalf("expected /v2 middleware to run once, ran %d times", hits)
+	}
+}
+
+func TestUseAfterRouteRegistration(t *testing.T) {
+	engine := New()
+	engine.GET("/late", func(c *Context) { c.String(http.StatusOK, "ok") })
+	engine.Use(func(c *Context) {
+		c.SetHeader("X-Late", "1")
+		c.Next()
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/late", nil)
+	engine.ServeHTTP(rec, req)
+	if rec.Header().Get("X-Late") != "1" {
+		t.Fatal("expected middleware added after registration to apply")
+	}
+}
+
+func TestContextPoolResetsState(t *testing.T) {
+	engine := New()
+	engine.GET("/set/:id", func(c *Context) {
+		c.Set("user", c.Param("id"))
+		c.String(http.StatusOK, "%s", c.GetString("user"))
+	})
+	engine.GET("/get", func(c *Context) {
+		c.String(http.StatusOK, "%s|%s|%d", c.GetString("user"), c.Param("id"), len(c.Params))
+	})
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/set/tom", nil)
+		engine.ServeHTTP(rec, req)
+		if rec.Body.String() != "tom" {
+			t.Fatalf("unexpected body %s", rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/get", nil)
+		engine.ServeHTTP(rec, req)
+		if rec.Body.String() != "||0" {
+			t.Fatalf("state leaked between pooled contexts: %s", rec.Body.String())
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package Gee
 
 import (
+	"fmt"
 	"html/template"
+	"io/fs"
 	"net/http"
-	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
 type HandlerFunc func(*Context)
@@ -13,15 +16,39 @@ type RouterGroup struct {
 	middlewares []HandlerFunc
 	parent      *RouterGroup
 	engine      *Engine
+	// host is nil for groups of the default host.
+	host *hostRoute
 }
 type Engine struct {
-	routerGroup   *RouterGroup
-	router        *Router
-	routerGroups  []*RouterGroup
-	htmlTemplates *template.Template
-	funcMap       template.FuncMap
-	noRoute       HandlerFunc
-	noMethod      HandlerFunc
+	// Server settings applied by Run, RunTLS, RunListener and RunUnix.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// MaxMultipartMemory is how much of a multipart body is kept in memory
+	// before file parts spill to temporary files.
+	MaxMultipartMemory int64
+	// ForwardedByClientIP makes Context.ClientIP trust X-Forwarded-For and
+	// X-Real-IP. Only enable it behind a proxy that sets those headers.
+	ForwardedByClientIP bool
+
+	routerGroup  *RouterGroup
+	router       *Router
+	routerGroups []*RouterGroup
+	hosts        []*hostRoute
+	hostRoutes   map[string]*hostRoute
+	constraints  map[string]func(string) bool
+	htmlRender   HTMLRender
+	funcMap      template.FuncMap
+	routeNames   map[string]*namedRoute
+	noRoute      HandlerFunc
+	noMethod     HandlerFunc
+	pool         sync.Pool
+	serverMu     sync.Mutex
+	servers      []*http.Server
+	onShutdown   []func()
+	shutdown     bool
 }
 
 func joinGroupPrefix(parentPrefix, childPrefix string) string {
@@ -67,9 +94,19 @@ var allHTTPMethods = []string{
 }
 
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:             newRouter(),
+		routeNames:         make(map[string]*namedRoute),
+		hostRoutes:         make(map[string]*hostRoute),
+		constraints:        make(map[string]func(string) bool),
+		MaxMultipartMemory: defaultMultipartMemory,
+	}
+	engine.router.constraints = engine.constraints
 	engine.routerGroup = &RouterGroup{engine: engine}
 	engine.routerGroups = []*RouterGroup{engine.routerGroup}
+	engine.pool.New = func() interface{} {
+		return &Context{engine: engine}
+	}
 	return engine
 }
 func (engine *Engine) Group(prefix string) *RouterGroup {
@@ -77,89 +114,190 @@ func (engine *Engine) Group(prefix string) *RouterGroup {
 }
 func (routerGroup *RouterGroup) Group(prefix string) *RouterGroup {
 	engine := routerGroup.engine
-	newGroup := &RouterGroup{prefix: joinGroupPrefix(routerGroup.prefix, prefix), parent: routerGroup, engine: engine}
+	newGroup := &RouterGroup{prefix: joinGroupPrefix(routerGroup.prefix, prefix), parent: routerGroup, engine: engine, host: routerGroup.host}
 	engine.routerGroups = append(engine.routerGroups, newGroup)
 	return newGroup
 }
-func (routerGroup *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+
+// matches reports whether the group applies to pattern. Prefixes are compared
+// by whole path segments, so a group "/v2" covers "/v2/x" but not "/v2x".
+func (routerGroup *RouterGroup) matches(pattern string) bool {
+	prefix := routerGroup.prefix
+	if prefix == "" {
+		return true
+	}
+	if !strings.HasPrefix(pattern, prefix) {
+		return false
+	}
+	return len(pattern) == len(prefix) || pattern[len(prefix)] == '/'
+}
+
+// combineHandlers resolves the middleware of every group of host covering
+// pattern, in the order the groups were created, followed by the given
+// handlers. The engine's own middleware covers every host.
+func (engine *Engine) combineHandlers(host *hostRoute, pattern string, handlers []HandlerFunc) []HandlerFunc {
+	chain := make([]HandlerFunc, 0, len(handlers))
+	for _, group := range engine.routerGroups {
+		if group == engine.routerGroup || group.host == host && group.matches(pattern) {
+			chain = append(chain, group.middlewares...)
+		}
+	}
+	return append(chain, handlers...)
+}
+
+// rebuildChains recompiles the chain of every registered route so that
+// middleware added after registration still applies.
+func (engine *Engine) rebuildChains() {
+	for _, leaf := range engine.router.leaves {
+		leaf.chain = engine.combineHandlers(nil, leaf.pattern, leaf.handlers)
+	}
+	for _, h := range engine.hosts {
+		for _, leaf := range h.router.leaves {
+			leaf.chain = engine.combineHandlers(h, leaf.pattern, leaf.handlers)
+		}
+	}
+}
+
+func (engine *Engine) fallbackChain(host *hostRoute, path string, handler HandlerFunc) []HandlerFunc {
+	return engine.combineHandlers(host, cleanPath(path), []HandlerFunc{handler})
+}
+
+// router returns the routing tree of the group's host.
+func (routerGroup *RouterGroup) router() *Router {
+	if routerGroup.host != nil {
+		return routerGroup.host.router
+	}
+	return routerGroup.engine.router
+}
+
+func (routerGroup *RouterGroup) addRoute(method string, comp string, handlers []HandlerFunc) *RouteRef {
 	pattern := joinRoutePath(routerGroup.prefix, comp)
-	routerGroup.engine.router.addRouter(method, pattern, handler)
+	if len(handlers) == 0 {
+		panic(fmt.Sprintf("Gee: route %s %s has no handler", method, pattern))
+	}
+	engine := routerGroup.engine
+	router := routerGroup.router()
+	leaf := router.addRouter(method, pattern, handlers)
+	leaf.chain = engine.combineHandlers(routerGroup.host, pattern, handlers)
+	return &RouteRef{engine: engine, router: router, indexes: []int{len(router.routes) - 1}}
 }
-func (engine *Engine) addRouter(method string, pattern string, handler HandlerFunc) {
-	engine.router.addRouter(method, joinRoutePath("", pattern), handler)
+func (engine *Engine) Handle(method string, pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute(method, pattern, handlers)
 }
-func (engine *Engine) Handle(method string, pattern string, handler HandlerFunc) {
-	engine.addRouter(method, pattern, handler)
+func (engine *Engine) GET(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("GET", pattern, handlers)
 }
-func (engine *Engine) GET(pattern string, handler HandlerFunc) {
-	engine.addRouter("GET", pattern, handler)
+func (routerGroup *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute(method, pattern, handlers)
 }
-func (routerGroup *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("GET", pattern, handler)
+func (routerGroup *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("GET", pattern, handlers)
 }
-func (routerGroup *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("POST", pattern, handler)
+func (routerGroup *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("POST", pattern, handlers)
 }
-func (routerGroup *RouterGroup) PUT(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("PUT", pattern, handler)
+func (routerGroup *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("PUT", pattern, handlers)
 }
-func (routerGroup *RouterGroup) DELETE(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("DELETE", pattern, handler)
+func (routerGroup *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("DELETE", pattern, handlers)
 }
-func (routerGroup *RouterGroup) PATCH(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("PATCH", pattern, handler)
+func (routerGroup *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("PATCH", pattern, handlers)
 }
-func (routerGroup *RouterGroup) HEAD(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("HEAD", pattern, handler)
+func (routerGroup *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("HEAD", pattern, handlers)
 }
-func (routerGroup *RouterGroup) OPTIONS(pattern string, handler HandlerFunc) {
-	routerGroup.addRoute("OPTIONS", pattern, handler)
+func (routerGroup *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return routerGroup.addRoute("OPTIONS", pattern, handlers)
 }
-func (routerGroup *RouterGroup) Any(pattern string, handler HandlerFunc) {
+func (routerGroup *RouterGroup) Any(pattern string, handlers ...HandlerFunc) *RouteRef {
+	ref := &RouteRef{engine: routerGroup.engine, router: routerGroup.router()}
 	for _, method := range allHTTPMethods {
-		routerGroup.addRoute(method, pattern, handler)
+		ref.indexes = append(ref.indexes, routerGroup.addRoute(method, pattern, handlers).indexes...)
 	}
+	return ref
 }
-func (engine *Engine) POST(pattern string, handler HandlerFunc) {
-	engine.addRouter("POST", pattern, handler)
+func (engine *Engine) POST(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("POST", pattern, handlers)
 }
-func (engine *Engine) PUT(pattern string, handler HandlerFunc) {
-	engine.addRouter("PUT", pattern, handler)
+func (engine *Engine) PUT(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("PUT", pattern, handlers)
 }
-func (engine *Engine) DELETE(pattern string, handler HandlerFunc) {
-	engine.addRouter("DELETE", pattern, handler)
+func (engine *Engine) DELETE(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("DELETE", pattern, handlers)
 }
-func (engine *Engine) PATCH(pattern string, handler HandlerFunc) {
-	engine.addRouter("PATCH", pattern, handler)
+func (engine *Engine) PATCH(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("PATCH", pattern, handlers)
 }
-func (engine *Engine) HEAD(pattern string, handler HandlerFunc) {
-	engine.addRouter("HEAD", pattern, handler)
+func (engine *Engine) HEAD(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("HEAD", pattern, handlers)
 }
-func (engine *Engine) OPTIONS(pattern string, handler HandlerFunc) {
-	engine.addRouter("OPTIONS", pattern, handler)
+func (engine *Engine) OPTIONS(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.addRoute("OPTIONS", pattern, handlers)
 }
-func (engine *Engine) Any(pattern string, handler HandlerFunc) {
-	for _, method := range allHTTPMethods {
-		engine.addRouter(method, pattern, handler)
-	}
+func (engine *Engine) Any(pattern string, handlers ...HandlerFunc) *RouteRef {
+	return engine.routerGroup.Any(pattern, handlers...)
 }
+
+// Routes lists the routes of the default host followed by those of each
+// host, in registration order.
 func (engine *Engine) Routes() []Route {
-	return engine.router.listRoutes()
-}
-func (engine *Engine) Run(addr string) error {
-	return http.ListenAndServe(addr, engine)
+	routes := engine.router.listRoutes()
+	for _, h := range engine.hosts {
+		routes = append(routes, h.router.routes...)
+	}
+	return routes
 }
 func (engine *Engine) Use(middleware ...HandlerFunc) {
-	engine.routerGroup.middlewares = append(engine.routerGroup.middlewares, middleware...)
+	engine.routerGroup.Use(middleware...)
 }
 func (routerGroup *RouterGroup) Use(middleware ...HandlerFunc) {
 	routerGroup.middlewares = append(routerGroup.middlewares, middleware...)
+	routerGroup.engine.rebuildChains()
 }
 func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.funcMap = funcMap
 }
+
+// TemplateFuncs returns the built-in template helpers overlaid with the
+// functions passed to SetFuncMap.
+func (engine *Engine) TemplateFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"url": engine.URL,
+		// Replaced per request under the CSRF middleware.
+		"csrfToken": func() string { return "" },
+	}
+	for name, fn := range engine.funcMap {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// SetHTMLRender replaces the renderer used by c.HTMLTemplate.
+func (engine *Engine) SetHTMLRender(render HTMLRender) {
+	engine.htmlRender = render
+}
+
+// LoadHTMLGlob renders the templates matching pattern by their file names.
+// It panics when they cannot be parsed.
 func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+	render := NewHTMLTemplates(nil, engine.TemplateFuncs())
+	if err := render.AddGlob(pattern); err != nil {
+		panic(err)
+	}
+	engine.htmlRender = render
+}
+
+// LoadHTMLFS is LoadHTMLGlob reading from fsys, such as an embed.FS, that
+// returns parse errors instead of panicking.
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) error {
+	render := NewHTMLTemplates(fsys, engine.TemplateFuncs())
+	if err := render.AddGlob(patterns...); err != nil {
+		return err
+	}
+	engine.htmlRender = render
+	return nil
 }
 func (engine *Engine) NoRoute(handler HandlerFunc) {
 	engine.noRoute = handler
@@ -168,33 +306,13 @@ func (engine *Engine) NoMethod(handler HandlerFunc) {
 	engine.noMethod = handler
 }
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middleWare []HandlerFunc
-	for _, group := range engine.routerGroups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middleWare = append(middleWare, group.middlewares...)
-		}
-	}
-	c := NewContext(w, req, engine)
-	c.handles = middleWare
-	engine.router.handle(c, engine.noRoute, engine.noMethod)
-}
-func (routerGroup *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := path.Join(routerGroup.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(c *Context) {
-		file := c.Param("filepath")
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
-		fileServer.ServeHTTP(c.Writer, c.Rep)
-	}
-
-}
-func (routerGroup *RouterGroup) Static(relativePath string, root string) {
-	handler := routerGroup.createStaticHandler(relativePath, http.Dir(root))
-	pattern := path.Join(relativePath, "/*filepath")
-	routerGroup.GET(pattern, handler)
+	c := engine.pool.Get().(*Context)
+	c.Reset(w, req)
+	engine.routerFor(req.Host).handle(c, engine.noRoute, engine.noMethod)
+	c.removeMultipartForm()
+	c.Rep = nil
+	c.writer.reset(nil)
+	engine.pool.Put(c)
 }
 
 func Default() *Engine {
@@ -0,0 +1,228 @@
+package Gee
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// HTMLRender renders the templates of c.HTMLTemplate. funcs holds
+// request-scoped functions, such as csrfToken, that replace the functions
+// of the same name for this call; it is nil when there are none.
+type HTMLRender interface {
+	Render(w io.Writer, name string, data interface{}, funcs template.FuncMap) error
+}
+
+// ErrTemplateNotFound is returned for names no template set defines.
+var ErrTemplateNotFound = errors.New("Gee: template not found")
+
+// HTMLTemplates is the default HTMLRender. It holds independent pages,
+// each parsed from a layout, partials and a page file, and a shared set
+// whose templates are rendered by their own names.
+type HTMLTemplates struct {
+	// Debug re-parses a set before rendering it when one of its files has
+	// changed, been added or been removed. Files are checked on every
+	// render, so keep it off in production.
+	Debug bool
+
+	fsys  fs.FS
+	funcs template.FuncMap
+
+	mu     sync.RWMutex
+	pages  map[string]*templateSet
+	shared *templateSet
+}
+
+// NewHTMLTemplates reads templates from fsys, or from the operating system
+// with paths used as given when fsys is nil. Pass engine.TemplateFuncs() as
+// funcs to keep the built-in helpers.
+func NewHTMLTemplates(fsys fs.FS, funcs template.FuncMap) *HTMLTemplates {
+	return &HTMLTemplates{fsys: fsys, funcs: funcs, pages: make(map[string]*templateSet)}
+}
+
+// AddPage parses the files matching patterns, in order, into a set of its
+// own rendered by executing the first file. A layout listed first can
+// declare {{block "content" .}} and the page file define "content".
+func (r *HTMLTemplates) AddPage(name string, patterns ...string) error {
+	set := &templateSet{r: r, patterns: patterns, byFile: true}
+	if err := set.load(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pages[name] = set
+	return nil
+}
+
+// AddPages adds a page for each file matching pattern, named by its path
+// and parsed after the files matching layout. Pages created after the call
+// are not picked up, even in debug mode.
+func (r *HTMLTemplates) AddPages(layout []string, pattern string) error {
+	files, err := r.glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("Gee: pattern %q matches no templates", pattern)
+	}
+	for _, file := range files {
+		if err := r.AddPage(file, append(append([]string(nil), layout...), file)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddGlob parses the files matching patterns into the shared set.
+func (r *HTMLTemplates) AddGlob(patterns ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	set := &templateSet{r: r}
+	if r.shared != nil {
+		set.patterns = append(set.patterns, r.shared.patterns...)
+	}
+	set.patterns = append(set.patterns, patterns...)
+	if err := set.load(); err != nil {
+		return err
+	}
+	r.shared = set
+	return nil
+}
+
+func (r *HTMLTemplates) Render(w io.Writer, name string, data interface{}, funcs template.FuncMap) error {
+	r.mu.RLock()
+	set, root := r.pages[name], ""
+	if set == nil && r.shared != nil {
+		set, root = r.shared, name
+	}
+	r.mu.RUnlock()
+	if set == nil {
+		return fmt.Errorf("%w: %q", ErrTemplateNotFound, name)
+	}
+	return set.execute(w, root, data, funcs)
+}
+
+func (r *HTMLTemplates) glob(pattern string) ([]string, error) {
+	if r.fsys == nil {
+		return filepath.Glob(pattern)
+	}
+	return fs.Glob(r.fsys, pattern)
+}
+func (r *HTMLTemplates) stat(name string) (fs.FileInfo, error) {
+	if r.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(r.fsys, name)
+}
+
+// templateSet is one template.Template with the patterns it is parsed from.
+type templateSet struct {
+	r        *HTMLTemplates
+	patterns []string
+	// byFile executes the first file rather than a template named by the
+	// caller.
+	byFile bool
+	parsed atomic.Pointer[parsedTemplates]
+}
+
+type parsedTemplates struct {
+	tmpl *template.Template
+	// pristine is never executed, so it can be cloned to bind
+	// request-scoped functions.
+	pristine *template.Template
+	root     string
+	stamp    string
+}
+
+// files expands the patterns and fingerprints the matches for Debug.
+func (set *templateSet) files() ([]string, string, error) {
+	var files []string
+	var stamp strings.Builder
+	for _, pattern := range set.patterns {
+		matches, err := set.r.glob(pattern)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(matches) == 0 {
+			return nil, "", fmt.Errorf("Gee: pattern %q matches no templates", pattern)
+		}
+		for _, file := range matches {
+			info, err := set.r.stat(file)
+			if err != nil {
+				return nil, "", err
+			}
+			files = append(files, file)
+			fmt.Fprintf(&stamp, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return files, stamp.String(), nil
+}
+
+func (set *templateSet) load() error {
+	files, stamp, err := set.files()
+	if err != nil {
+		return err
+	}
+	return set.parse(files, stamp)
+}
+
+func (set *templateSet) parse(files []string, stamp string) error {
+	t := template.New("").Funcs(set.r.funcs)
+	var err error
+	if set.r.fsys == nil {
+		t, err = t.ParseFiles(files...)
+	} else {
+		t, err = t.ParseFS(set.r.fsys, files...)
+	}
+	if err != nil {
+		return fmt.Errorf("Gee: parse templates: %w", err)
+	}
+	pristine, err := t.Clone()
+	if err != nil {
+		return err
+	}
+	p := &parsedTemplates{tmpl: t, pristine: pristine, stamp: stamp}
+	if set.byFile {
+		p.root = path.Base(filepath.ToSlash(files[0]))
+	}
+	set.parsed.Store(p)
+	return nil
+}
+
+func (set *templateSet) execute(w io.Writer, name string, data interface{}, funcs template.FuncMap) error {
+	if set.r.Debug {
+		files, stamp, err := set.files()
+		if err != nil {
+			return err
+		}
+		if stamp != set.parsed.Load().stamp {
+			if err := set.parse(files, stamp); err != nil {
+				return err
+			}
+		}
+	}
+	p := set.parsed.Load()
+	if set.byFile {
+		name = p.root
+	}
+	t := p.tmpl
+	if funcs != nil {
+		clone, err := p.pristine.Clone()
+		if err != nil {
+			return err
+		}
+		t = clone.Funcs(funcs)
+	}
+	if t.Lookup(name) == nil {
+		return fmt.Errorf("%w: %q", ErrTemplateNotFound, name)
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
@@ -0,0 +1,188 @@
+package Gee
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func renderPage(engine *Engine, name string, data interface{}) *httptest.ResponseRecorder {
+	engine.GET("/render", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, c.Query("name"), data)
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/render?name="+name, nil))
+	return rec
+}
+
+func TestHTMLTemplatesLayouts(t *testing.T) {
+	views := fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`<title>{{block "title" .}}Admin{{end}}</title><main>{{block "content" .}}{{end}}</main>{{template "footer.html" .}}`)},
+		"partials/footer.html": {Data: []byte(`<footer>{{url "home"}}</footer>`)},
+		"pages/home.html":      {Data: []byte(`{{define "content"}}hello {{.Name}}{{end}}`)},
+		"pages/users.html":     {Data: []byte(`{{define "title"}}Users{{end}}{{define "content"}}{{len .}} users{{end}}`)},
+		"emails/welcome.txt":   {Data: []byte(`{{define "welcome"}}welcome {{.}}{{end}}`)},
+	}
+	engine := New()
+	engine.GET("/", func(c *Context) {}).Name("home")
+	render := NewHTMLTemplates(views, engine.TemplateFuncs())
+	if err := render.AddPages([]string{"layouts/base.html", "partials/*.html"}, "pages/*.html"); err != nil {
+		t.Fatal(err)
+	}
+	if err := render.AddGlob("emails/*.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		data interface{}
+		body string
+	}{
+		{"pages/home.html", H{"Name": "<tom>"}, `<title>Admin</title><main>hello &lt;tom&gt;</main><footer>/</footer>`},
+		{"pages/users.html", []int{1, 2}, `<title>Users</title><main>2 users</main><footer>/</footer>`},
+		{"welcome", "tom", `welcome tom`},
+	}
+	for _, tc := range cases {
+		// Each request gets a fresh engine; url still resolves against the
+		// engine whose functions were parsed in.
+		page := New()
+		page.SetHTMLRender(render)
+		if rec := renderPage(page, tc.name, tc.data); rec.Code != http.StatusOK || rec.Body.String() != tc.body {
+			t.Fatalf("%s: got %d %q", tc.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	var buf strings.Builder
+	if err := render.Render(&buf, "pages/missing.html", nil, nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+	if err := render.AddPage("broken", "pages/none-*.html"); err == nil {
+		t.Fatal("expected an error for a pattern matching nothing")
+	}
+}
+
+func TestHTMLTemplatesDebugReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(page, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`v1 {{.}}`)
+	render := NewHTMLTemplates(os.DirFS(dir), nil)
+	if err := render.AddPage("page", "*.html"); err != nil {
+		t.Fatal(err)
+	}
+	rendered := func() (string, error) {
+		var buf strings.Builder
+		err := render.Render(&buf, "page", "x", nil)
+		return buf.String(), err
+	}
+
+	write(`version 2 {{.}}`)
+	if body, _ := rendered(); body != "v1 x" {
+		t.Fatalf("templates must not reload outside debug mode, got %q", body)
+	}
+	render.Debug = true
+	if body, err := rendered(); err != nil || body != "version 2 x" {
+		t.Fatalf("expected the changed template, got %q %v", body, err)
+	}
+	write(`{{.Broken`)
+	if _, err := rendered(); err == nil {
+		t.Fatal("expected the parse error of the changed template")
+	}
+	write(`version three {{.}}`)
+	if body, err := rendered(); err != nil || body != "version three x" {
+		t.Fatalf("expected recovery after fixing the template, got %q %v", body, err)
+	}
+}
+
+func TestLoadHTMLErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.tmpl"), []byte(`{{if}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := New()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected LoadHTMLGlob to panic on a parse error")
+			}
+		}()
+		engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	}()
+	if err := engine.LoadHTMLFS(fstest.MapFS{"bad.tmpl": {Data: []byte(`{{if}}`)}}, "*.tmpl"); err == nil {
+		t.Fatal("expected LoadHTMLFS to return the parse error")
+	}
+	if err := engine.LoadHTMLFS(fstest.MapFS{"ok.tmpl": {Data: []byte(`ok`)}}, "*.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+	if rec := renderPage(engine, "ok.tmpl", nil); rec.Body.String() != "ok" {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestHTMLTemplatesRequestFuncs(t *testing.T) {
+	render := NewHTMLTemplates(fstest.MapFS{
+		"form.tmpl": {Data: []byte(`{{define "form"}}{{csrfToken}}-{{join "a" "b"}}{{end}}`)},
+	}, template.FuncMap{
+		"csrfToken": func() string { return "" },
+		"join":      func(parts ...string) string { return strings.Join(parts, "_") },
+	})
+	if err := render.AddGlob("*.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var buf strings.Builder
+				funcs := template.FuncMap{"csrfToken": func() string { return token }}
+				if err := render.Render(&buf, "form", nil, funcs); err != nil || buf.String() != token+"-a_b" {
+					t.Errorf("expected token %q, got %q %v", token, buf.String(), err)
+					return
+				}
+			}
+		}(fmt.Sprint("t", i))
+	}
+	wg.Wait()
+
+	var buf strings.Builder
+	if err := render.Render(&buf, "form", nil, nil); err != nil || buf.String() != "-a_b" {
+		t.Fatalf("expected the registered csrfToken without request funcs, got %q %v", buf.String(), err)
+	}
+}
+
+type upperRender struct{}
+
+func (upperRender) Render(w io.Writer, name string, data interface{}, funcs template.FuncMap) error {
+	token := ""
+	if fn, ok := funcs["csrfToken"].(func() string); ok {
+		token = fn()
+	}
+	_, err := fmt.Fprintf(w, "%s:%v:%t", strings.ToUpper(name), data, token != "")
+	return err
+}
+
+func TestCustomHTMLRender(t *testing.T) {
+	engine := New()
+	engine.SetHTMLRender(upperRender{})
+	engine.Use(CSRF(CSRFConfig{}))
+	if rec := renderPage(engine, "page", 7); rec.Body.String() != "PAGE:7:true" {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,137 @@
+package Gee
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hostRoute is a host pattern with its own routing tree. Labels written
+// as {name} match any single label and are exposed through c.Param.
+type hostRoute struct {
+	pattern string
+	labels  []string
+	params  int
+	router  *Router
+}
+
+// Host returns a group whose routes only match requests for pattern, such
+// as "api.example.com" or "{tenant}.example.com". Ports are ignored.
+// Middleware added with engine.Use applies to every host; groups of the
+// default host do not. Requests for other hosts, and paths a host routes
+// under no method, are served by the default host.
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	h := engine.hostRoutes[strings.ToLower(pattern)]
+	if h == nil {
+		h = newHostRoute(pattern)
+		h.router.host = h
+		h.router.constraints = engine.constraints
+		engine.hostRoutes[h.pattern] = h
+		engine.hosts = append(engine.hosts, h)
+		// Exact hosts first, then those with fewer params.
+		sort.SliceStable(engine.hosts, func(i, j int) bool {
+			return engine.hosts[i].params < engine.hosts[j].params
+		})
+	}
+	group := &RouterGroup{parent: engine.routerGroup, engine: engine, host: h}
+	engine.routerGroups = append(engine.routerGroups, group)
+	return group
+}
+
+func newHostRoute(pattern string) *hostRoute {
+	pattern = strings.ToLower(pattern)
+	h := &hostRoute{pattern: pattern, labels: strings.Split(pattern, "."), router: newRouter()}
+	if pattern == "" || strings.ContainsAny(pattern, ":/") {
+		panic(fmt.Sprintf("Gee: invalid host pattern %q", pattern))
+	}
+	seen := make(map[string]bool)
+	for _, label := range h.labels {
+		if label == "" || strings.ContainsAny(label, "{}") && !isHostParam(label) {
+			panic(fmt.Sprintf("Gee: invalid host pattern %q", pattern))
+		}
+		if isHostParam(label) {
+			name := label[1 : len(label)-1]
+			if seen[name] {
+				panic(fmt.Sprintf("Gee: host pattern %q repeats param %q", pattern, name))
+			}
+			seen[name] = true
+			h.params++
+		}
+	}
+	return h
+}
+
+func isHostParam(label string) bool {
+	return len(label) > 2 && label[0] == '{' && label[len(label)-1] == '}' && !strings.ContainsAny(label[1:len(label)-1], "{}")
+}
+
+// match reports whether host, already normalized, matches h. With capture
+// set, the host params are appended to params.
+func (h *hostRoute) match(host string, params Params, capture bool) (Params, bool) {
+	for i, label := range h.labels {
+		var value string
+		if i == len(h.labels)-1 {
+			value, host = host, ""
+		} else {
+			var ok bool
+			if value, host, ok = strings.Cut(host, "."); !ok {
+				return params, false
+			}
+		}
+		if value == "" || strings.IndexByte(value, '.') >= 0 {
+			return params, false
+		}
+		if isHostParam(label) {
+			if !capture {
+				continue
+			}
+			params = append(params, Param{Key: label[1 : len(label)-1], Value: value})
+		} else if value != label {
+			return params, false
+		}
+	}
+	return params, true
+}
+
+// routerFor picks the routing tree serving host.
+func (engine *Engine) routerFor(host string) *Router {
+	if len(engine.hosts) == 0 {
+		return engine.router
+	}
+	host = normalizeHost(host)
+	if h := engine.hostRoutes[host]; h != nil && h.params == 0 {
+		return h.router
+	}
+	for _, h := range engine.hosts {
+		if h.params == 0 {
+			continue
+		}
+		if _, ok := h.match(host, nil, false); ok {
+			return h.router
+		}
+	}
+	return engine.router
+}
+
+// servingRouter picks the routing tree that handles path on host, falling
+// back to the default host like Router.handle does.
+func (engine *Engine) servingRouter(host string, path string) *Router {
+	router := engine.routerFor(host)
+	if router.host != nil && !router.pathExists(path) {
+		return engine.router
+	}
+	return router
+}
+
+// normalizeHost strips the port and trailing dot from a Host header and
+// lower-cases it.
+func normalizeHost(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if end := strings.IndexByte(host, ']'); end > 0 {
+			host = host[:end+1]
+		}
+	} else if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host, ':') == i {
+		host = host[:i]
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,110 @@
+package Gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func hostRequest(engine *Engine, method, host, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Host = host
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHostRouting(t *testing.T) {
+	engine := New()
+	var trail []string
+	engine.Use(func(c *Context) {
+		trail = append(trail, "global")
+		c.Next()
+	})
+	engine.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+	engine.Group("/users").Use(func(c *Context) {
+		trail = append(trail, "default-users")
+		c.Next()
+	})
+
+	api := engine.Host("API.example.com")
+	api.Use(func(c *Context) {
+		trail = append(trail, "api")
+		c.Next()
+	})
+	api.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "api user %s", c.Param("id"))
+	}).Name("api.user")
+
+	tenants := engine.Host("{tenant}.example.com")
+	tenants.Group("/shop").GET("/:item", func(c *Context) {
+		c.String(http.StatusOK, "%s sells %s", c.Param("tenant"), c.Param("item"))
+	})
+	engine.Host("{region}.{tenant}.example.com").GET("/", func(c *Context) {
+		c.String(http.StatusOK, "%s in %s", c.Param("tenant"), c.Param("region"))
+	})
+
+	cases := []struct {
+		host, target, body string
+		code               int
+	}{
+		{"api.example.com", "/users/7", "api user 7", http.StatusOK},
+		{"api.example.com:8443", "/users/7", "api user 7", http.StatusOK},
+		{"Api.Example.Com.", "/users/7", "api user 7", http.StatusOK},
+		{"acme.example.com", "/shop/anvil", "acme sells anvil", http.StatusOK},
+		{"eu.acme.example.com:80", "/", "acme in eu", http.StatusOK},
+		{"api.example.com", "/", "default", http.StatusOK},
+		{"api.example.com", "/missing", "404 page not found", http.StatusNotFound},
+		{"acme.example.com", "/users/7", "404 page not found", http.StatusNotFound},
+		{"example.com", "/", "default", http.StatusOK},
+		{"[::1]:8080", "/", "default", http.StatusOK},
+		{"a.b.c.example.com", "/", "default", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rec := hostRequest(engine, http.MethodGet, tc.host, tc.target)
+		if rec.Code != tc.code || rec.Body.String() != tc.body {
+			t.Fatalf("%s%s: got %d %q", tc.host, tc.target, rec.Code, rec.Body.String())
+		}
+	}
+
+	trail = nil
+	hostRequest(engine, http.MethodGet, "api.example.com", "/users/7")
+	if strings.Join(trail, ",") != "global,api" {
+		t.Fatalf("host routes must run global and host middleware only, got %v", trail)
+	}
+	trail = nil
+	hostRequest(engine, http.MethodGet, "api.example.com", "/")
+	if strings.Join(trail, ",") != "global" {
+		t.Fatalf("paths falling through to the default host must skip host middleware, got %v", trail)
+	}
+	if rec := hostRequest(engine, http.MethodPost, "api.example.com", "/users/7"); rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET" {
+		t.Fatalf("expected 405 from the host tree, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	var hosts []string
+	for _, route := range engine.Routes() {
+		hosts = append(hosts, route.Host+route.Pattern)
+	}
+	if got := strings.Join(hosts, " "); got != "/ api.example.com/users/:id {tenant}.example.com/shop/:item {region}.{tenant}.example.com/" {
+		t.Fatalf("unexpected routes %s", got)
+	}
+	if u, err := engine.URL("api.user", "id", 7); err != nil || u != "/users/7" {
+		t.Fatalf("unexpected URL %q %v", u, err)
+	}
+}
+
+func TestHostPatternValidation(t *testing.T) {
+	for _, pattern := range []string{"", "example.com:80", "{a}.{a}.com", "{}.example.com", "x{y}.example.com", "a..com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for host %q", pattern)
+				}
+			}()
+			New().Host(pattern)
+		}()
+	}
+}
@@ -0,0 +1,285 @@
+package Gee
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWTClaimsKey is the Context key holding the claims of a verified token.
+const JWTClaimsKey = "jwt_claims"
+
+var (
+	ErrJWTMalformed   = errors.New("Gee: malformed token")
+	ErrJWTSignature   = errors.New("Gee: invalid token signature")
+	ErrJWTUnknownKey  = errors.New("Gee: unknown token key")
+	ErrJWTAlgorithm   = errors.New("Gee: unsupported token algorithm")
+	ErrJWTExpired     = errors.New("Gee: token is expired")
+	ErrJWTNotValidYet = errors.New("Gee: token is not valid yet")
+	ErrJWTIssuer      = errors.New("Gee: token issuer mismatch")
+	ErrJWTAudience    = errors.New("Gee: token audience mismatch")
+)
+
+// JWTConfig configures the JWT middleware. Verification keys are []byte for
+// HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey on P-256 for ES256; a
+// token is only accepted with the algorithm matching its key's type.
+type JWTConfig struct {
+	// Key verifies tokens without a "kid" header.
+	Key interface{}
+	// Keys verifies tokens by their "kid" header, so keys can be rotated by
+	// publishing a new kid before retiring the old one.
+	Keys map[string]interface{}
+	// KeyFunc, when set, replaces Key and Keys, for keys that change at
+	// runtime.
+	KeyFunc func(kid string) (interface{}, error)
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+	// Realm is announced in WWW-Authenticate.
+	Realm string
+	// TokenFunc extracts the token; it defaults to the Bearer token of the
+	// Authorization header.
+	TokenFunc func(*Context) string
+}
+
+// JWTClaims are the decoded claims of a token. Numbers decode as
+// json.Number.
+type JWTClaims map[string]interface{}
+
+func (claims JWTClaims) String(name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+func (claims JWTClaims) Subject() string {
+	return claims.String("sub")
+}
+func (claims JWTClaims) Issuer() string {
+	return claims.String("iss")
+}
+
+// Audience returns the aud claim, which may be a single string or a list.
+func (claims JWTClaims) Audience() []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		out := make([]string, 0, len(aud))
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Time returns a NumericDate claim such as exp, nbf or iat.
+func (claims JWTClaims) Time(name string) (time.Time, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
+// JWTClaims returns the claims stored by the JWT middleware, or nil.
+func (c *Context) JWTClaims() JWTClaims {
+	if value, ok := c.Get(JWTClaimsKey); ok {
+		claims, _ := value.(JWTClaims)
+		return claims
+	}
+	return nil
+}
+
+// JWTSubject returns the sub claim of the verified token.
+func (c *Context) JWTSubject() string {
+	return c.JWTClaims().Subject()
+}
+
+func bearerToken(c *Context) string {
+	scheme, token, ok := strings.Cut(c.Rep.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// JWT verifies the bearer token of each request and stores its claims under
+// JWTClaimsKey. Failures answer 401 with an RFC 6750 challenge.
+func JWT(config JWTConfig) HandlerFunc {
+	if config.Key == nil && len(config.Keys) == 0 && config.KeyFunc == nil {
+		panic("Gee: JWT needs a verification key")
+	}
+	if config.Realm == "" {
+		config.Realm = "Authorization Required"
+	}
+	if config.TokenFunc == nil {
+		config.TokenFunc = bearerToken
+	}
+	challenge := "Bearer realm=" + strconv.Quote(config.Realm)
+
+	return func(c *Context) {
+		token := config.TokenFunc(c)
+		if token == "" {
+			c.SetHeader("WWW-Authenticate", challenge)
+			c.Fail(http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		claims, err := config.verify(token, time.Now())
+		if err != nil {
+			message := strings.TrimPrefix(err.Error(), "Gee: ")
+			c.SetHeader("WWW-Authenticate", challenge+`, error="invalid_token", error_description=`+strconv.Quote(message))
+			c.Fail(http.StatusUnauthorized, message)
+			return
+		}
+		c.Set(JWTClaimsKey, claims)
+		c.Next()
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (config *JWTConfig) key(kid string) (interface{}, error) {
+	if config.KeyFunc != nil {
+		return config.KeyFunc(kid)
+	}
+	if kid == "" {
+		if config.Key == nil {
+			return nil, ErrJWTUnknownKey
+		}
+		return config.Key, nil
+	}
+	if key, ok := config.Keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrJWTUnknownKey
+}
+
+func (config *JWTConfig) verify(token string, now time.Time) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	key, err := config.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+	var claims JWTClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if exp, ok := claims.Time("exp"); ok && !now.Before(exp.Add(config.Leeway)) {
+		return nil, ErrJWTExpired
+	} else if !ok && claims["exp"] != nil {
+		return nil, ErrJWTMalformed
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(config.Leeway).Before(nbf) {
+		return nil, ErrJWTNotValidYet
+	} else if !ok && claims["nbf"] != nil {
+		return nil, ErrJWTMalformed
+	}
+	if config.Issuer != "" && claims.Issuer() != config.Issuer {
+		return nil, ErrJWTIssuer
+	}
+	if config.Audience != "" {
+		found := false
+		for _, aud := range claims.Audience() {
+			if aud == config.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrJWTAudience
+		}
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+// verifyJWTSignature checks signature with the algorithm the key type
+// allows, so an RSA public key can never be abused as an HMAC secret.
+func verifyJWTSignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch key := key.(type) {
+	case []byte:
+		if alg != "HS256" {
+			return ErrJWTAlgorithm
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrJWTSignature
+		}
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			return ErrJWTAlgorithm
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return ErrJWTSignature
+		}
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || key.Curve != elliptic.P256() {
+			return ErrJWTAlgorithm
+		}
+		if len(signature) != 64 {
+			return ErrJWTSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return ErrJWTSignature
+		}
+	default:
+		return fmt.Errorf("Gee: unsupported JWT key type %T", key)
+	}
+	return nil
+}
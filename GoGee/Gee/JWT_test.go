@@ -0,0 +1,146 @@
+package Gee
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signTestJWT(t *testing.T, alg string, kid string, key interface{}, claims H) string {
+	t.Helper()
+	header := H{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwtRequest(engine *Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func newJWTEngine(config JWTConfig) *Engine {
+	engine := New()
+	engine.Use(JWT(config))
+	engine.GET("/me", func(c *Context) {
+		c.String(http.StatusOK, "%s %s", c.JWTSubject(), c.JWTClaims().String("role"))
+	})
+	return engine
+}
+
+func TestJWTAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("shared-secret")
+	engine := newJWTEngine(JWTConfig{Keys: map[string]interface{}{
+		"hs": secret,
+		"rs": &rsaKey.PublicKey,
+		"es": &ecKey.PublicKey,
+	}})
+	claims := H{"sub": "alice", "role": "admin", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for _, tc := range []struct {
+		alg, kid string
+		key      interface{}
+	}{{"HS256", "hs", secret}, {"RS256", "rs", rsaKey}, {"ES256", "es", ecKey}} {
+		rec := jwtRequest(engine, signTestJWT(t, tc.alg, tc.kid, tc.key, claims))
+		if rec.Code != http.StatusOK || rec.Body.String() != "alice admin" {
+			t.Fatalf("%s: expected claims, got %d %q", tc.alg, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Signed with the right key but announced under another kid.
+	rec := jwtRequest(engine, signTestJWT(t, "HS256", "rs", secret, claims))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("expected algorithm mismatch to fail, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := jwtRequest(engine, signTestJWT(t, "HS256", "old", secret, claims)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unknown kid to fail, got %d", rec.Code)
+	}
+	if rec := jwtRequest(engine, signTestJWT(t, "HS256", "hs", []byte("forged"), claims)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected bad signature to fail, got %d", rec.Code)
+	}
+	rec = jwtRequest(engine, "")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Bearer realm="Authorization Required"` {
+		t.Fatalf("expected bare challenge, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestJWTRegisteredClaims(t *testing.T) {
+	secret := []byte("shared-secret")
+	engine := newJWTEngine(JWTConfig{Key: secret, Issuer: "auth.example.com", Audience: "api", Leeway: time.Minute})
+	now := time.Now()
+	valid := H{"sub": "bob", "iss": "auth.example.com", "aud": []string{"web", "api"}, "exp": now.Add(time.Hour).Unix()}
+
+	if rec := jwtRequest(engine, signTestJWT(t, "HS256", "", secret, valid)); rec.Code != http.StatusOK {
+		t.Fatalf("expected valid token to pass, got %d %s", rec.Code, rec.Body.String())
+	}
+	cases := map[string]H{
+		"token is expired":        {"iss": "auth.example.com", "aud": "api", "exp": now.Add(-2 * time.Minute).Unix()},
+		"token is not valid yet":  {"iss": "auth.example.com", "aud": "api", "nbf": now.Add(2 * time.Minute).Unix()},
+		"token issuer mismatch":   {"iss": "evil.example.com", "aud": "api"},
+		"token audience mismatch": {"iss": "auth.example.com", "aud": "web"},
+	}
+	for message, claims := range cases {
+		rec := jwtRequest(engine, signTestJWT(t, "HS256", "", secret, claims))
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), message) {
+			t.Fatalf("expected %q, got %d %s", message, rec.Code, rec.Body.String())
+		}
+	}
+	within := H{"iss": "auth.example.com", "aud": "api", "exp": now.Add(-30 * time.Second).Unix()}
+	if rec := jwtRequest(engine, signTestJWT(t, "HS256", "", secret, within)); rec.Code != http.StatusOK {
+		t.Fatalf("expected leeway to accept a just-expired token, got %d", rec.Code)
+	}
+}
@@ -1,19 +1,146 @@
 package Gee
 
 import (
-	"log"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
 	"time"
 )
 
+// Fields LoggerWithConfig can emit.
+const (
+	LogMethod         = "method"
+	LogPath           = "path"
+	LogRoute          = "route"
+	LogStatus         = "status"
+	LogLatency        = "latency"
+	LogLatencyBefore  = "latency_before"
+	LogLatencyHandler = "latency_handler"
+	LogLatencyAfter   = "latency_after"
+	LogClientIP       = "client_ip"
+	LogBytes          = "bytes"
+	LogUserAgent      = "user_agent"
+	LogRequestID      = "request_id"
+	LogError          = "error"
+)
+
+var DefaultLogFields = []string{
+	LogMethod, LogPath, LogRoute, LogStatus, LogLatency, LogLatencyBefore, LogLatencyHandler,
+	LogLatencyAfter, LogClientIP, LogBytes, LogUserAgent, LogRequestID, LogError,
+}
+
+// LoggerConfig configures LoggerWithConfig.
+type LoggerConfig struct {
+	// Logger receives the records. When nil, one is built from Output and
+	// JSON, and when Output is nil too, slog.Default() is used.
+	Logger *slog.Logger
+	Output io.Writer
+	JSON   bool
+	// Fields selects the attributes of each record; nil means
+	// DefaultLogFields.
+	Fields []string
+	// SkipPaths are request paths, such as "/healthz", that are never logged.
+	SkipPaths []string
+	// SampleSuccess logs only one in every SampleSuccess requests answered
+	// below 400. Zero or one logs them all.
+	SampleSuccess uint64
+}
+
 func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithConfig writes one structured record per request. Latency is
+// split into the time spent before the route handler, in it, and after it.
+// Responses of 500 and above are logged at error level, 400 and above at
+// warn level, others at info level.
+func LoggerWithConfig(config LoggerConfig) HandlerFunc {
+	logger := config.Logger
+	if logger == nil && config.Output != nil {
+		if config.JSON {
+			logger = slog.New(slog.NewJSONHandler(config.Output, nil))
+		} else {
+			logger = slog.New(slog.NewTextHandler(config.Output, nil))
+		}
+	}
+	fields := make(map[string]bool)
+	if config.Fields == nil {
+		config.Fields = DefaultLogFields
+	}
+	for _, field := range config.Fields {
+		fields[field] = true
+	}
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, p := range config.SkipPaths {
+		skip[p] = true
+	}
+	var successes atomic.Uint64
+
 	return func(c *Context) {
-		t := time.Now()
+		c.baseLogger = logger
+		if skip[c.Path] {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.timing = true
 		c.Next()
-		requestID := c.GetString("request_id")
-		if requestID != "" {
-			log.Printf("[%d] %s %s in %v", c.StatusCode, requestID, c.Rep.RequestURI, time.Since(t))
+		end := time.Now()
+
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest && config.SampleSuccess > 1 && (successes.Add(1)-1)%config.SampleSuccess != 0 {
+			return
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		out := logger
+		if out == nil {
+			out = slog.Default()
+		}
+		if !out.Enabled(context.Background(), level) {
 			return
 		}
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Rep.RequestURI, time.Since(t))
+
+		attrs := make([]slog.Attr, 0, len(fields))
+		add := func(field string, value slog.Value) {
+			if fields[field] {
+				attrs = append(attrs, slog.Attr{Key: field, Value: value})
+			}
+		}
+		add(LogMethod, slog.StringValue(c.Method))
+		add(LogPath, slog.StringValue(c.Path))
+		if route := c.FullPath(); route != "" {
+			add(LogRoute, slog.StringValue(route))
+		}
+		add(LogStatus, slog.IntValue(status))
+		add(LogLatency, slog.DurationValue(end.Sub(start)))
+		if !c.handlerStart.IsZero() {
+			handlerEnd := c.handlerEnd
+			if handlerEnd.IsZero() {
+				// The handler panicked.
+				handlerEnd = end
+			}
+			add(LogLatencyBefore, slog.DurationValue(c.handlerStart.Sub(start)))
+			add(LogLatencyHandler, slog.DurationValue(handlerEnd.Sub(c.handlerStart)))
+			add(LogLatencyAfter, slog.DurationValue(end.Sub(handlerEnd)))
+		}
+		add(LogClientIP, slog.StringValue(c.ClientIP()))
+		add(LogBytes, slog.IntValue(max(c.Writer.Size(), 0)))
+		add(LogUserAgent, slog.StringValue(c.Rep.UserAgent()))
+		if requestID := c.GetString("request_id"); requestID != "" {
+			add(LogRequestID, slog.StringValue(requestID))
+		}
+		if len(c.errs) > 0 {
+			add(LogError, slog.StringValue(errors.Join(c.errs...).Error()))
+		}
+		out.LogAttrs(c.Rep.Context(), level, "request", attrs...)
 	}
 }
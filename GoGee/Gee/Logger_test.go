@@ -0,0 +1,93 @@
+package Gee
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggerWithConfigJSON(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(RequestID(), LoggerWithConfig(LoggerConfig{Output: &buf, JSON: true, SkipPaths: []string{"/healthz"}}), Recovery())
+	engine.GET("/users/:id", func(c *Context) {
+		c.Logger().Info("loading user", "id", c.Param("id"))
+		time.Sleep(5 * time.Millisecond)
+		c.String(http.StatusOK, "ok")
+	})
+	engine.GET("/healthz", func(c *Context) { c.String(http.StatusOK, "ok") })
+	engine.GET("/boom", func(c *Context) { panic("kaboom") })
+
+	for _, path := range []string{"/users/7", "/healthz", "/boom"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", "gee-test")
+		req.Header.Set("X-Request-ID", "req-"+path[1:3])
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	records := decodeLogLines(t, &buf)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d: %v", len(records), records)
+	}
+	scoped, access, failure := records[0], records[1], records[2]
+	if scoped["msg"] != "loading user" || scoped["request_id"] != "req-us" || scoped["id"] != "7" {
+		t.Fatalf("unexpected request-scoped record %v", scoped)
+	}
+	if access["level"] != "INFO" || access["method"] != "GET" || access["path"] != "/users/7" || access["route"] != "/users/:id" ||
+		access["status"] != float64(200) || access["bytes"] != float64(2) || access["user_agent"] != "gee-test" ||
+		access["request_id"] != "req-us" || access["client_ip"] != "192.0.2.1" {
+		t.Fatalf("unexpected access record %v", access)
+	}
+	if handler, _ := access["latency_handler"].(float64); handler < float64(5*time.Millisecond) {
+		t.Fatalf("expected handler latency of at least 5ms, got %v", access["latency_handler"])
+	}
+	if _, ok := access["latency_before"]; !ok {
+		t.Fatalf("missing latency_before in %v", access)
+	}
+	if failure["level"] != "ERROR" || failure["status"] != float64(500) || failure["error"] != "panic: kaboom" {
+		t.Fatalf("unexpected failure record %v", failure)
+	}
+}
+
+func TestLoggerWithConfigFieldsAndSampling(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(LoggerWithConfig(LoggerConfig{Output: &buf, JSON: true, Fields: []string{LogStatus}, SampleSuccess: 3}))
+	engine.GET("/ok", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	for i := 0; i < 6; i++ {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	records := decodeLogLines(t, &buf)
+	if len(records) != 3 {
+		t.Fatalf("expected 2 sampled successes and 1 failure, got %v", records)
+	}
+	if records[2]["status"] != float64(404) || records[2]["level"] != "WARN" {
+		t.Fatalf("unexpected failure record %v", records[2])
+	}
+	for _, record := range records {
+		if _, ok := record["path"]; ok || len(record) != 4 {
+			t.Fatalf("expected only time, level, msg and status, got %v", record)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package Gee
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	DefaultSizeBuckets    = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// MetricsConfig configures a MetricsCollector.
+type MetricsConfig struct {
+	// Namespace prefixes every metric name; it defaults to "gee".
+	Namespace string
+	// LatencyBuckets are upper bounds in seconds, SizeBuckets in bytes.
+	LatencyBuckets []float64
+	SizeBuckets    []float64
+}
+
+// MetricsCollector records HTTP metrics labeled by method, route pattern and
+// status, and renders them in the Prometheus text exposition format.
+// Requests matching no route share the route label "unmatched", so raw
+// paths never become labels.
+type MetricsCollector struct {
+	requestsName string
+	inFlightName string
+	latencyName  string
+	sizeName     string
+
+	latencyBuckets []float64
+	sizeBuckets    []float64
+
+	mu       sync.RWMutex
+	series   map[metricsKey]*metricsSeries
+	inFlight map[metricsKey]*atomic.Int64
+}
+
+type metricsKey struct {
+	method string
+	route  string
+	status int
+}
+
+type metricsSeries struct {
+	mu      sync.Mutex
+	count   uint64
+	latency histogram
+	size    histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds))
+	}
+	if i := sort.SearchFloat64s(bounds, v); i < len(bounds) {
+		h.counts[i]++
+	}
+	h.sum += v
+}
+
+// DefaultMetricsCollector backs Metrics and MetricsHandler.
+var DefaultMetricsCollector = NewMetricsCollector(MetricsConfig{})
+
+// Metrics records every request in DefaultMetricsCollector.
+func Metrics() HandlerFunc {
+	return DefaultMetricsCollector.Middleware()
+}
+
+// MetricsHandler serves DefaultMetricsCollector; register it on the route
+// scraped by Prometheus, for example engine.GET("/metrics", MetricsHandler()).
+func MetricsHandler() HandlerFunc {
+	return DefaultMetricsCollector.Handler()
+}
+
+func NewMetricsCollector(config MetricsConfig) *MetricsCollector {
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = "gee"
+	}
+	m := &MetricsCollector{
+		requestsName:   namespace + "_http_requests_total",
+		inFlightName:   namespace + "_http_requests_in_flight",
+		latencyName:    namespace + "_http_request_duration_seconds",
+		sizeName:       namespace + "_http_response_size_bytes",
+		latencyBuckets: checkBuckets(config.LatencyBuckets, DefaultLatencyBuckets),
+		sizeBuckets:    checkBuckets(config.SizeBuckets, DefaultSizeBuckets),
+		series:         make(map[metricsKey]*metricsSeries),
+		inFlight:       make(map[metricsKey]*atomic.Int64),
+	}
+	return m
+}
+
+func checkBuckets(buckets []float64, fallback []float64) []float64 {
+	if len(buckets) == 0 {
+		return fallback
+	}
+	for i, b := range buckets {
+		if math.IsNaN(b) || (i > 0 && b <= buckets[i-1]) {
+			panic(fmt.Sprintf("Gee: metrics buckets must be increasing, got %v", buckets))
+		}
+	}
+	out := make([]float64, len(buckets))
+	copy(out, buckets)
+	return out
+}
+
+// metricsMethod folds unknown methods together so clients cannot create
+// series at will.
+func metricsMethod(method string) string {
+	for _, known := range allHTTPMethods {
+		if method == known {
+			return known
+		}
+	}
+	return "OTHER"
+}
+
+func (m *MetricsCollector) Middleware() HandlerFunc {
+	return func(c *Context) {
+		key := metricsKey{method: metricsMethod(c.Method), route: c.FullPath()}
+		if key.route == "" {
+			key.route = "unmatched"
+		}
+		gauge := m.gauge(key)
+		gauge.Add(1)
+		start := time.Now()
+		defer func() {
+			// Deferred so panicking handlers still leave the gauge.
+			gauge.Add(-1)
+		}()
+		c.Next()
+
+		key.status = c.Writer.Status()
+		s := m.seriesFor(key)
+		s.mu.Lock()
+		s.count++
+		s.latency.observe(m.latencyBuckets, time.Since(start).Seconds())
+		s.size.observe(m.sizeBuckets, float64(max(c.Writer.Size(), 0)))
+		s.mu.Unlock()
+	}
+}
+
+func (m *MetricsCollector) gauge(key metricsKey) *atomic.Int64 {
+	m.mu.RLock()
+	g := m.inFlight[key]
+	m.mu.RUnlock()
+	if g != nil {
+		return g
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if g = m.inFlight[key]; g == nil {
+		g = new(atomic.Int64)
+		m.inFlight[key] = g
+	}
+	return g
+}
+
+func (m *MetricsCollector) seriesFor(key metricsKey) *metricsSeries {
+	m.mu.RLock()
+	s := m.series[key]
+	m.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s = m.series[key]; s == nil {
+		s = &metricsSeries{}
+		m.series[key] = s
+	}
+	return s
+}
+
+// Handler serves the collected metrics in the text exposition format.
+func (m *MetricsCollector) Handler() HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		_, _ = m.WriteTo(c.Writer)
+	}
+}
+
+type metricsSnapshot struct {
+	key     metricsKey
+	count   uint64
+	latency histogram
+	size    histogram
+}
+
+// WriteTo renders every metric in the Prometheus text exposition format,
+// with series sorted by labels.
+func (m *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	m.mu.RLock()
+	snapshots := make([]metricsSnapshot, 0, len(m.series))
+	for key, s := range m.series {
+		s.mu.Lock()
+		snapshots = append(snapshots, metricsSnapshot{
+			key:     key,
+			count:   s.count,
+			latency: histogram{counts: append([]uint64(nil), s.latency.counts...), sum: s.latency.sum},
+			size:    histogram{counts: append([]uint64(nil), s.size.counts...), sum: s.size.sum},
+		})
+		s.mu.Unlock()
+	}
+	gauges := make([]metricsKey, 0, len(m.inFlight))
+	values := make(map[metricsKey]int64, len(m.inFlight))
+	for key, g := range m.inFlight {
+		gauges = append(gauges, key)
+		values[key] = g.Load()
+	}
+	m.mu.RUnlock()
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].key.less(snapshots[j].key) })
+	sort.Slice(gauges, func(i, j int) bool { return gauges[i].less(gauges[j]) })
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	writeMetricHeader(cw, m.requestsName, "counter", "Total number of HTTP requests.")
+	for _, s := range snapshots {
+		fmt.Fprintf(cw, "%s{%s} %d\n", m.requestsName, s.key.labels(), s.count)
+	}
+	writeMetricHeader(cw, m.inFlightName, "gauge", "Number of HTTP requests being served.")
+	for _, key := range gauges {
+		fmt.Fprintf(cw, "%s{method=%s,route=%s} %d\n", m.inFlightName, quoteLabel(key.method), quoteLabel(key.route), values[key])
+	}
+	writeMetricHeader(cw, m.latencyName, "histogram", "HTTP request latency in seconds.")
+	for _, s := range snapshots {
+		writeHistogram(cw, m.latencyName, s.key.labels(), m.latencyBuckets, s.latency, s.count)
+	}
+	writeMetricHeader(cw, m.sizeName, "histogram", "HTTP response body size in bytes.")
+	for _, s := range snapshots {
+		writeHistogram(cw, m.sizeName, s.key.labels(), m.sizeBuckets, s.size, s.count)
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func (k metricsKey) less(o metricsKey) bool {
+	if k.route != o.route {
+		return k.route < o.route
+	}
+	if k.method != o.method {
+		return k.method < o.method
+	}
+	return k.status < o.status
+}
+
+func (k metricsKey) labels() string {
+	return "method=" + quoteLabel(k.method) + ",route=" + quoteLabel(k.route) + ",status=\"" + strconv.Itoa(k.status) + "\""
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatMetricFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeMetricHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w io.Writer, name string, labels string, bounds []float64, h histogram, count uint64) {
+	var cumulative uint64
+	for i, bound := range bounds {
+		if i < len(h.counts) {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatMetricFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatMetricFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, count)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
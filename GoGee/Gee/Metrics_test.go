@@ -0,0 +1,70 @@
+package Gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	metrics := NewMetricsCollector(MetricsConfig{Namespace: "app", LatencyBuckets: []float64{0.05, 1}, SizeBuckets: []float64{10, 1000}})
+	engine := New()
+	engine.Use(metrics.Middleware())
+	engine.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, "user %s", c.Param("id")) })
+	engine.GET("/slow", func(c *Context) {
+		time.Sleep(60 * time.Millisecond)
+		c.String(http.StatusCreated, "%s", strings.Repeat("x", 100))
+	})
+	engine.GET("/metrics", metrics.Handler())
+
+	for _, path := range []string{"/users/1", "/users/2", "/slow", "/nope", "/nope/again"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/users/1", nil))
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE app_http_requests_total counter",
+		`app_http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`app_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`app_http_requests_total{method="OTHER",route="unmatched",status="405"} 1`,
+		"# TYPE app_http_requests_in_flight gauge",
+		`app_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		`app_http_requests_in_flight{method="GET",route="/slow"} 0`,
+		"# TYPE app_http_request_duration_seconds histogram",
+		`app_http_request_duration_seconds_bucket{method="GET",route="/slow",status="201",le="0.05"} 0`,
+		`app_http_request_duration_seconds_bucket{method="GET",route="/slow",status="201",le="1"} 1`,
+		`app_http_request_duration_seconds_bucket{method="GET",route="/slow",status="201",le="+Inf"} 1`,
+		`app_http_request_duration_seconds_count{method="GET",route="/slow",status="201"} 1`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/:id",status="200",le="10"} 2`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/slow",status="201",le="10"} 0`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/slow",status="201",le="1000"} 1`,
+		`app_http_response_size_bytes_sum{method="GET",route="/users/:id",status="200"} 12`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("missing %q in exposition:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "/users/1") || strings.Contains(body, "/nope") {
+		t.Fatalf("raw paths must not become labels:\n%s", body)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	if got := quoteLabel("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Fatalf("unexpected escaping %s", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected unsorted buckets to panic")
+		}
+	}()
+	NewMetricsCollector(MetricsConfig{LatencyBuckets: []float64{1, 0.5}})
+}
@@ -0,0 +1,106 @@
+package Gee
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func isMultipart(req *http.Request) bool {
+	return strings.HasPrefix(strings.ToLower(req.Header.Get("Content-Type")), "multipart/form-data")
+}
+
+func (c *Context) maxMultipartMemory() int64 {
+	if c.engine != nil && c.engine.MaxMultipartMemory > 0 {
+		return c.engine.MaxMultipartMemory
+	}
+	return defaultMultipartMemory
+}
+
+// MultipartForm parses a multipart body, keeping up to the engine's
+// MaxMultipartMemory in memory and spilling larger files to disk. The files
+// are removed when the request finishes.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.Rep.MultipartForm == nil {
+		if err := c.Rep.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+			return nil, err
+		}
+	}
+	c.multipartForm = c.Rep.MultipartForm
+	return c.Rep.MultipartForm, nil
+}
+
+// removeMultipartForm deletes the temporary files of the parsed form.
+func (c *Context) removeMultipartForm() {
+	if c.multipartForm != nil {
+		_ = c.multipartForm.RemoveAll()
+	}
+	if c.Rep != nil && c.Rep.MultipartForm != nil && c.Rep.MultipartForm != c.multipartForm {
+		_ = c.Rep.MultipartForm.RemoveAll()
+	}
+}
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// MultipartReader gives streaming access to the parts of a multipart body so
+// large uploads can be processed without buffering. It cannot be combined
+// with MultipartForm, FormFile or PostForm on the same request.
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	return c.Rep.MultipartReader()
+}
+
+// SaveUploadedFile copies fh to dst, creating missing parent directories.
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// IsBodyTooLarge reports whether err was caused by a BodyLimit cap.
+func IsBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// BodyLimit caps the request body at limit bytes. Requests announcing a
+// larger Content-Length are rejected with 413 up front; otherwise reads past
+// the limit fail and Bind reports 413 as well.
+func BodyLimit(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Rep.ContentLength > limit {
+			c.Fail(http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		if c.Rep.Body != nil && c.Rep.Body != http.NoBody {
+			c.Rep.Body = http.MaxBytesReader(c.Writer, c.Rep.Body, limit)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,195 @@
+package Gee
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newUploadRequest(t *testing.T, path string, field string, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("owner", "tom")
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatalf("create form file failed: %v", err)
+	}
+	_, _ = fw.Write(content)
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormFileAndSave(t *testing.T) {
+	engine := New()
+	engine.MaxMultipartMemory = 16
+	dir := t.TempDir()
+	engine.POST("/upload", func(c *Context) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		dst := filepath.Join(dir, "nested", fh.Filename)
+		if err := c.SaveUploadedFile(fh, dst); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s:%d", c.PostForm("owner"), fh.Size)
+	})
+
+	content := []byte(strings.Repeat("gee", 100))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, newUploadRequest(t, "/upload", "file", "a.txt", content))
+	if rec.Code != http.StatusOK || rec.Body.String() != "tom:300" {
+		t.Fatalf("unexpected upload response %d %s", rec.Code, rec.Body.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "nested", "a.txt"))
+	if err != nil || !bytes.Equal(saved, content) {
+		t.Fatalf("saved file mismatch: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, newUploadRequest(t, "/upload", "other", "a.txt", content))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for missing file, got %d", rec.Code)
+	}
+}
+
+func TestMultipartTempFilesRemoved(t *testing.T) {
+	engine := New()
+	engine.MaxMultipartMemory = 16
+	engine.Use(func(c *Context) {
+		// Like Tracing, replace the request the server knows about.
+		c.Rep = c.Rep.WithContext(c.Rep.Context())
+		c.Next()
+	})
+	var spilled string
+	engine.POST("/upload", func(c *Context) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer f.Close()
+		if file, ok := f.(*os.File); ok {
+			spilled = file.Name()
+		}
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, newUploadRequest(t, "/upload", "file", "a.txt", []byte(strings.Repeat("gee", 100))))
+	if rec.Code != http.StatusOK || spilled == "" {
+		t.Fatalf("expected the upload to spill to disk, got %d %q", rec.Code, spilled)
+	}
+	if _, err := os.Stat(spilled); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed after the request, got %v", spilled, err)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	engine := New()
+	engine.POST("/small", BodyLimit(64), func(c *Context) {
+		if _, err := c.FormFile("file"); err != nil {
+			if IsBodyTooLarge(err) {
+				c.Fail(http.StatusRequestEntityTooLarge, "too large")
+				return
+			}
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	engine.POST("/json", BodyLimit(8), func(c *Context) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if c.Bind(&body) == nil {
+			c.String(http.StatusOK, "ok")
+		}
+	})
+	engine.POST("/big", func(c *Context) {
+		if _, err := c.FormFile("file"); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, newUploadRequest(t, "/small", "file", "a.bin", make([]byte, 1024)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 from Content-Length check, got %d", rec.Code)
+	}
+
+	req := newUploadRequest(t, "/small", "file", "a.bin", make([]byte, 1024))
+	req.ContentLength = -1
+	req.Body = io.NopCloser(req.Body)
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 from streaming limit, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"name":"a-long-name"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected Bind to report 413, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, newUploadRequest(t, "/big", "file", "a.bin", make([]byte, 1024)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("routes without BodyLimit should accept the upload, got %d", rec.Code)
+	}
+}
+
+func TestMultipartReaderStreamsParts(t *testing.T) {
+	engine := New()
+	engine.POST("/stream", func(c *Context) {
+		mr, err := c.MultipartReader()
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		var names []string
+		var total int64
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				c.Fail(http.StatusBadRequest, err.Error())
+				return
+			}
+			n, _ := io.Copy(io.Discard, part)
+			names = append(names, part.FormName())
+			total += n
+		}
+		c.String(http.StatusOK, "%s:%d", strings.Join(names, ","), total)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, newUploadRequest(t, "/stream", "file", "a.bin", make([]byte, 4096)))
+	if rec.Body.String() != "owner,file:4099" {
+		t.Fatalf("unexpected streamed parts %s", rec.Body.String())
+	}
+}
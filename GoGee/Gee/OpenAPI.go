@@ -0,0 +1,521 @@
+package Gee
+
+import (
+	"encoding"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RouteDoc describes a route for the OpenAPI document.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Request is a value of the type bound by the handler. Fields tagged
+	// `uri`, `query` or `header` become parameters, the others the JSON
+	// body; `binding` rules become schema constraints.
+	Request interface{}
+	// Responses maps status codes to a value of the response body type, or
+	// nil for responses without a body.
+	Responses map[int]interface{}
+	// Params documents parameters the Request type does not cover.
+	Params []ParamDoc
+}
+
+// ParamDoc documents one path, query or header parameter.
+type ParamDoc struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	// Type is a value of the parameter's Go type; nil means string.
+	Type interface{}
+}
+
+// Doc attaches OpenAPI metadata to the route.
+func (ref *RouteRef) Doc(doc RouteDoc) *RouteRef {
+	for _, index := range ref.indexes {
+		d := doc
+		ref.router.routes[index].Doc = &d
+	}
+	return ref
+}
+
+// OpenAPIInfo fills the info and servers sections of the document.
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+}
+
+// OpenAPI builds an OpenAPI 3.1 document from the registered routes,
+// reflecting over the Go types given in their RouteDoc.
+func (engine *Engine) OpenAPI(info OpenAPIInfo) H {
+	schemas := &openAPISchemas{defs: make(H), names: make(map[reflect.Type]string), constraints: engine.constraints}
+	paths := make(H)
+	for _, route := range engine.Routes() {
+		path, pathParams := openAPIPath(route.Pattern)
+		item, _ := paths[path].(H)
+		if item == nil {
+			item = make(H)
+			paths[path] = item
+		}
+		method := strings.ToLower(route.Method)
+		op, exists := item[method].(H)
+		if !exists {
+			op = schemas.operation(route, pathParams)
+			item[method] = op
+			if route.Host != "" {
+				op["servers"] = []H{}
+			}
+		}
+		// A path and method routed on several hosts is documented once, from
+		// its first route, with a server per host.
+		if servers, ok := op["servers"].([]H); ok && route.Host != "" {
+			op["servers"] = append(servers, openAPIHostServer(route.Host))
+		}
+	}
+
+	infoObject := H{"title": info.Title, "version": info.Version}
+	if info.Title == "" {
+		infoObject["title"] = "API"
+	}
+	if info.Version == "" {
+		infoObject["version"] = "0.0.0"
+	}
+	if info.Description != "" {
+		infoObject["description"] = info.Description
+	}
+	doc := H{"openapi": "3.1.0", "info": infoObject, "paths": paths}
+	if len(info.Servers) > 0 {
+		servers := make([]H, len(info.Servers))
+		for i, url := range info.Servers {
+			servers[i] = H{"url": url}
+		}
+		doc["servers"] = servers
+	}
+	if len(schemas.defs) > 0 {
+		doc["components"] = H{"schemas": schemas.defs}
+	}
+	return doc
+}
+
+// OpenAPIHandler serves the document as JSON, rebuilt on each request so
+// it always reflects the routes registered so far.
+func (engine *Engine) OpenAPIHandler(info OpenAPIInfo) HandlerFunc {
+	return func(c *Context) {
+		c.JSON(http.StatusOK, engine.OpenAPI(info))
+	}
+}
+
+var openAPIDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<redoc spec-url="{{.SpecURL}}"></redoc>
+<script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`))
+
+// OpenAPIDocsHandler serves a static page rendering the document found at
+// specURL with ReDoc.
+func OpenAPIDocsHandler(title string, specURL string) HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		_ = openAPIDocsTemplate.Execute(c.Writer, H{"Title": title, "SpecURL": specURL})
+	}
+}
+
+// openAPIPath converts "/users/:id/*path" to "/users/{id}/{path}". An
+// unnamed catch-all is documented as {wildcard}.
+func openAPIPath(pattern string) (string, [][2]string) {
+	parts := parsePattern(pattern)
+	params := make([][2]string, 0)
+	for i, part := range parts {
+		if part[0] == ':' || part[0] == '*' {
+			name, constraint := splitParam(part[1:])
+			if name == "" {
+				name = openAPIWildcardName(params)
+			}
+			params = append(params, [2]string{name, constraint})
+			parts[i] = "{" + name + "}"
+		}
+	}
+	return "/" + strings.Join(parts, "/"), params
+}
+
+func openAPIWildcardName(params [][2]string) string {
+	name := "wildcard"
+	for n := 1; ; n++ {
+		taken := false
+		for _, p := range params {
+			taken = taken || p[0] == name
+		}
+		if !taken {
+			return name
+		}
+		name = "wildcard" + strconv.Itoa(n)
+	}
+}
+
+// openAPIHostServer describes a host pattern as a server relative to the
+// scheme of the document, with a variable per {param} label.
+func openAPIHostServer(host string) H {
+	server := H{"url": "//" + host}
+	variables := H{}
+	for _, label := range strings.Split(host, ".") {
+		if isHostParam(label) {
+			name := label[1 : len(label)-1]
+			variables[name] = H{"default": name}
+		}
+	}
+	if len(variables) > 0 {
+		server["variables"] = variables
+	}
+	return server
+}
+
+// constraintSchema describes a path param by its route constraint. Custom
+// constraints are opaque and documented as plain strings.
+func (s *openAPISchemas) constraintSchema(constraint string) H {
+	switch {
+	case constraint == "int":
+		return H{"type": "integer", "format": "int64"}
+	case constraint == "uuid":
+		return H{"type": "string", "format": "uuid"}
+	case constraint == "":
+		return H{"type": "string"}
+	}
+	if _, ok := s.constraints[constraint]; ok {
+		return H{"type": "string"}
+	}
+	return H{"type": "string", "pattern": "^(?:" + constraint + ")$"}
+}
+
+type openAPISchemas struct {
+	defs        H
+	names       map[reflect.Type]string
+	constraints map[string]func(string) bool
+}
+
+func (s *openAPISchemas) operation(route Route, pathParams [][2]string) H {
+	op := H{}
+	doc := route.Doc
+	if doc == nil {
+		doc = &RouteDoc{}
+	}
+	if route.Name != "" {
+		op["operationId"] = route.Name
+	}
+	if doc.Summary != "" {
+		op["summary"] = doc.Summary
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if len(doc.Tags) > 0 {
+		op["tags"] = doc.Tags
+	}
+	if doc.Deprecated {
+		op["deprecated"] = true
+	}
+
+	params := make([]H, 0)
+	seen := make(map[string]bool)
+	addParam := func(p H) {
+		key := p["in"].(string) + ":" + p["name"].(string)
+		if !seen[key] {
+			seen[key] = true
+			params = append(params, p)
+		}
+	}
+	for _, p := range doc.Params {
+		param := H{"name": p.Name, "in": p.In, "schema": H{"type": "string"}}
+		if p.Type != nil {
+			param["schema"] = s.schema(reflect.TypeOf(p.Type))
+		}
+		if p.Description != "" {
+			param["description"] = p.Description
+		}
+		if p.Required || p.In == "path" {
+			param["required"] = true
+		}
+		addParam(param)
+	}
+	var body H
+	if doc.Request != nil {
+		t := reflect.TypeOf(doc.Request)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			for _, p := range s.requestParams(t) {
+				addParam(p)
+			}
+			if schema := s.bodySchema(t); route.Method != http.MethodGet && route.Method != http.MethodHead && schema != nil {
+				body = schema
+			}
+		} else {
+			body = s.schema(t)
+		}
+	}
+	for _, param := range pathParams {
+		addParam(H{"name": param[0], "in": "path", "required": true, "schema": s.constraintSchema(param[1])})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = H{"required": true, "content": H{"application/json": H{"schema": body}}}
+	}
+
+	responses := make(H)
+	for status, value := range doc.Responses {
+		response := H{"description": http.StatusText(status)}
+		if value != nil {
+			response["content"] = H{"application/json": H{"schema": s.schema(reflect.TypeOf(value))}}
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	if len(responses) == 0 {
+		responses["200"] = H{"description": http.StatusText(http.StatusOK)}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// requestParams lists the uri, query and header fields of a request type.
+func (s *openAPISchemas) requestParams(t reflect.Type) []H {
+	params := make([]H, 0)
+	for _, sf := range structFields(t) {
+		for _, in := range [][2]string{{"uri", "path"}, {"query", "query"}, {"header", "header"}} {
+			name, _, _ := strings.Cut(sf.Tag.Get(in[0]), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			schema := s.schema(sf.Type)
+			applyBindingRules(schema, sf)
+			param := H{"name": name, "in": in[1], "schema": schema}
+			if in[1] == "path" || hasBindingRule(sf, "required") {
+				param["required"] = true
+			}
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// bodySchema describes the JSON body of a request type, leaving out fields
+// bound only from the path, query string or headers.
+func (s *openAPISchemas) bodySchema(t reflect.Type) H {
+	schema := s.schema(t)
+	if !isParamOnly(t) {
+		return schema
+	}
+	return nil
+}
+
+func isParamOnly(t reflect.Type) bool {
+	for _, sf := range structFields(t) {
+		if !isParamField(sf) {
+			return false
+		}
+	}
+	return true
+}
+
+func isParamField(sf reflect.StructField) bool {
+	if _, ok := sf.Tag.Lookup("json"); ok {
+		return false
+	}
+	for _, tag := range []string{"uri", "query", "header"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(tag), ","); name != "" && name != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// structFields flattens embedded structs the way encoding/json does.
+func structFields(t reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if _, tagged := sf.Tag.Lookup("json"); !tagged && ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft)...)
+				continue
+			}
+		}
+		if sf.IsExported() {
+			fields = append(fields, sf)
+		}
+	}
+	return fields
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// schema returns the JSON Schema of t, registering named structs under
+// components/schemas and referring to them by $ref.
+func (s *openAPISchemas) schema(t reflect.Type) H {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return H{"type": "string", "format": "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return H{"type": "string"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return H{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return H{"type": "string"}
+	case reflect.Bool:
+		return H{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return H{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return H{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return H{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return H{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return H{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return H{"type": "string", "contentEncoding": "base64"}
+		}
+		return H{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return H{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name := s.register(t)
+		return H{"$ref": "#/components/schemas/" + name}
+	}
+	return H{}
+}
+
+func (s *openAPISchemas) register(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := s.defs[name]; taken {
+		name = strings.NewReplacer("/", ".", "[", "_", "]", "_").Replace(t.PkgPath() + "." + t.Name())
+	}
+	s.names[t] = name
+	s.defs[name] = H{} // placeholder for recursive types
+	s.defs[name] = s.structSchema(t)
+	return name
+}
+
+func (s *openAPISchemas) structSchema(t reflect.Type) H {
+	properties := make(H)
+	required := make([]string, 0)
+	for _, sf := range structFields(t) {
+		if isParamField(sf) {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		schema := s.schema(sf.Type)
+		applyBindingRules(schema, sf)
+		properties[name] = schema
+		if hasBindingRule(sf, "required") {
+			required = append(required, name)
+		}
+	}
+	schema := H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func hasBindingRule(sf reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(sf.Tag.Get("binding"), ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBindingRules mirrors the rules of the default validator as schema
+// constraints.
+func applyBindingRules(schema H, sf reflect.StructField) {
+	if _, isRef := schema["$ref"]; isRef {
+		return
+	}
+	for _, rule := range strings.Split(sf.Tag.Get("binding"), ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch rule {
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			var lower, upper string
+			switch schema["type"] {
+			case "string":
+				lower, upper = "minLength", "maxLength"
+			case "array":
+				lower, upper = "minItems", "maxItems"
+			case "object":
+				lower, upper = "minProperties", "maxProperties"
+			default:
+				lower, upper = "minimum", "maximum"
+			}
+			if rule != "max" {
+				schema[lower] = limit
+			}
+			if rule != "min" {
+				schema[upper] = limit
+			}
+		case "email":
+			schema["format"] = "email"
+		case "oneof":
+			options := strings.Fields(param)
+			enum := make([]interface{}, len(options))
+			for i, option := range options {
+				enum[i] = option
+				if schema["type"] == "integer" || schema["type"] == "number" {
+					if n, err := strconv.ParseFloat(option, 64); err == nil {
+						enum[i] = n
+					}
+				}
+			}
+			schema["enum"] = enum
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package Gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type openAPIAddress struct {
+	City string `json:"city" binding:"required"`
+}
+
+type openAPIUser struct {
+	ID        int64            `json:"id"`
+	Name      string           `json:"name" binding:"required,min=2,max=32"`
+	Email     string           `json:"email,omitempty" binding:"email"`
+	Role      string           `json:"role" binding:"oneof=admin member"`
+	Tags      []string         `json:"tags"`
+	Address   *openAPIAddress  `json:"address,omitempty"`
+	Friends   []openAPIUser    `json:"friends,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	Extra     map[string]int32 `json:"extra,omitempty"`
+	internal  string
+}
+
+type openAPIUpdateUser struct {
+	ID      int    `uri:"id"`
+	DryRun  bool   `query:"dry_run"`
+	TraceID string `header:"X-Trace-ID" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+}
+
+type openAPIListUsers struct {
+	Page  int    `query:"page" binding:"min=1"`
+	Query string `query:"q"`
+}
+
+func TestEngineOpenAPI(t *testing.T) {
+	engine := New()
+	users := engine.Group("/users")
+	users.GET("", func(c *Context) {}).Name("users.list").Doc(RouteDoc{
+		Summary:   "List users",
+		Tags:      []string{"users"},
+		Request:   openAPIListUsers{},
+		Responses: map[int]interface{}{http.StatusOK: []openAPIUser{}},
+	})
+	users.PUT("/:id", func(c *Context) {}).Doc(RouteDoc{
+		Request:   &openAPIUpdateUser{},
+		Responses: map[int]interface{}{http.StatusOK: openAPIUser{}, http.StatusNotFound: nil},
+	})
+	engine.GET("/files/*filepath", func(c *Context) {})
+	engine.GET("/assets/*", func(c *Context) {})
+	engine.Host("{tenant}.example.com").GET("/orders", func(c *Context) {})
+	engine.Host("shop.example.com").GET("/orders", func(c *Context) {})
+	engine.GET("/openapi.json", engine.OpenAPIHandler(OpenAPIInfo{Title: "Users", Version: "1.0.0", Servers: []string{"https://api.example.com"}}))
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected spec, got %d", rec.Code)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	// Reduce the document to comparable JSON snippets.
+	get := func(path ...string) string {
+		var v interface{} = doc
+		for _, key := range path {
+			v = v.(map[string]interface{})[key]
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+
+	if doc["openapi"] != "3.1.0" || get("info") != `{"title":"Users","version":"1.0.0"}` || get("servers") != `[{"url":"https://api.example.com"}]` {
+		t.Fatalf("unexpected header %v %s %s", doc["openapi"], get("info"), get("servers"))
+	}
+	if got := get("paths", "/files/{filepath}", "get"); got != `{"parameters":[{"in":"path","name":"filepath","required":true,"schema":{"type":"string"}}],"responses":{"200":{"description":"OK"}}}` {
+		t.Fatalf("unexpected catch-all operation %s", got)
+	}
+	if got := get("paths", "/assets/{wildcard}", "get", "parameters"); got != `[{"in":"path","name":"wildcard","required":true,"schema":{"type":"string"}}]` {
+		t.Fatalf("unexpected unnamed catch-all params %s", got)
+	}
+	if got := get("paths", "/orders", "get", "servers"); got != `[{"url":"//shop.example.com"},{"url":"//{tenant}.example.com","variables":{"tenant":{"default":"tenant"}}}]` {
+		t.Fatalf("unexpected host servers %s", got)
+	}
+	list := get("paths", "/users", "get")
+	for _, want := range []string{
+		`"operationId":"users.list"`,
+		`"summary":"List users"`,
+		`"tags":["users"]`,
+		`{"in":"query","name":"page","schema":{"format":"int64","minimum":1,"type":"integer"}}`,
+		`"200":{"content":{"application/json":{"schema":{"items":{"$ref":"#/components/schemas/openAPIUser"},"type":"array"}}}`,
+	} {
+		if !strings.Contains(list, want) {
+			t.Fatalf("list operation %s lacks %s", list, want)
+		}
+	}
+	if strings.Contains(list, "requestBody") {
+		t.Fatalf("GET must not have a body: %s", list)
+	}
+
+	update := get("paths", "/users/{id}", "put")
+	for _, want := range []string{
+		`{"in":"path","name":"id","required":true,"schema":{"format":"int64","type":"integer"}}`,
+		`{"in":"query","name":"dry_run","schema":{"type":"boolean"}}`,
+		`{"in":"header","name":"X-Trace-ID","required":true,"schema":{"type":"string"}}`,
+		`"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/openAPIUpdateUser"}}},"required":true}`,
+		`"404":{"description":"Not Found"}`,
+	} {
+		if !strings.Contains(update, want) {
+			t.Fatalf("update operation %s lacks %s", update, want)
+		}
+	}
+	if strings.Count(update, `"name":"id"`) != 1 {
+		t.Fatalf("path param documented twice: %s", update)
+	}
+
+	if got := get("components", "schemas", "openAPIUpdateUser"); got != `{"properties":{"name":{"type":"string"}},"required":["name"],"type":"object"}` {
+		t.Fatalf("unexpected body schema %s", got)
+	}
+	user := get("components", "schemas", "openAPIUser")
+	for _, want := range []string{
+		`"name":{"maxLength":32,"minLength":2,"type":"string"}`,
+		`"email":{"format":"email","type":"string"}`,
+		`"role":{"enum":["admin","member"],"type":"string"}`,
+		`"address":{"$ref":"#/components/schemas/openAPIAddress"}`,
+		`"friends":{"items":{"$ref":"#/components/schemas/openAPIUser"},"type":"array"}`,
+		`"created_at":{"format":"date-time","type":"string"}`,
+		`"extra":{"additionalProperties":{"format":"int32","type":"integer"},"type":"object"}`,
+		`"required":["name"]`,
+	} {
+		if !strings.Contains(user, want) {
+			t.Fatalf("user schema %s lacks %s", user, want)
+		}
+	}
+	if strings.Contains(user, "internal") {
+		t.Fatalf("unexported fields must be skipped: %s", user)
+	}
+}
+
+func TestOpenAPIDocsHandler(t *testing.T) {
+	engine := New()
+	engine.GET("/docs", OpenAPIDocsHandler("Users API", "/openapi.json?v=1&x=<y>"))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	body := rec.Body.String()
+	if rec.Header().Get("Content-Type") != "text/html; charset=utf-8" || !strings.Contains(body, "<title>Users API</title>") {
+		t.Fatalf("unexpected docs page %v %s", rec.Header(), body)
+	}
+	if !strings.Contains(body, `spec-url="/openapi.json?v=1&amp;x=%3cy%3e"`) {
+		t.Fatalf("spec URL not escaped: %s", body)
+	}
+}
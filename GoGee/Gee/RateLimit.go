@@ -0,0 +1,313 @@
+package Gee
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket refills Limit tokens per Window into a bucket holding up to
+	// Burst tokens; each request takes one.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests in any Window, estimated from the
+	// counts of the current and previous fixed windows.
+	SlidingWindow
+)
+
+// RateLimitRule is the quota a store enforces for a key.
+type RateLimitRule struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Window    time.Duration
+	// Burst is the bucket capacity of TokenBucket; zero means Limit.
+	Burst int
+}
+
+func (rule RateLimitRule) capacity() int {
+	if rule.Algorithm == TokenBucket && rule.Burst > 0 {
+		return rule.Burst
+	}
+	return rule.Limit
+}
+
+// RateLimitResult is the outcome of taking one request from a quota. Reset
+// is how long until the quota is fully available again.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps limiter state. Take must be atomic per key, since
+// several requests, possibly from several processes, share one key.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Window    time.Duration
+	Burst     int
+	// Store defaults to a MemoryRateLimitStore private to the middleware.
+	Store RateLimitStore
+	// KeyFunc names the quota a request counts against; it defaults to
+	// KeyByIP. Requests for which it returns "" are not limited.
+	KeyFunc func(*Context) string
+	// OnLimited writes the rejection after the rate limit headers are set.
+	// It defaults to a 429 JSON response.
+	OnLimited HandlerFunc
+	// OnError handles store failures. By default the request is let
+	// through, so an unavailable store does not take the service down.
+	OnError func(c *Context, err error)
+}
+
+// KeyByIP limits each client address separately.
+func KeyByIP() func(*Context) string {
+	return func(c *Context) string {
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// KeyByHeader limits each value of header separately, falling back to the
+// client address when the header is missing.
+func KeyByHeader(header string) func(*Context) string {
+	return func(c *Context) string {
+		if value := c.Rep.Header.Get(header); value != "" {
+			return "header:" + header + ":" + value
+		}
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// KeyByRoute shares one quota between all clients of a route.
+func KeyByRoute() func(*Context) string {
+	return func(c *Context) string {
+		return "route:" + c.Method + " " + c.FullPath()
+	}
+}
+
+// RateLimit rejects requests over the configured quota with 429 Too Many
+// Requests. Every limited response carries RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset; rejections add Retry-After.
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	rule := RateLimitRule{Algorithm: config.Algorithm, Limit: config.Limit, Window: config.Window, Burst: config.Burst}
+	if rule.Limit <= 0 || rule.Window <= 0 || rule.Burst < 0 {
+		panic(fmt.Sprintf("Gee: invalid rate limit %d per %s", rule.Limit, rule.Window))
+	}
+	if rule.Algorithm != TokenBucket && rule.Algorithm != SlidingWindow {
+		panic(fmt.Sprintf("Gee: unknown rate limit algorithm %d", rule.Algorithm))
+	}
+	store := config.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore(0)
+	}
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP()
+	}
+	onLimited := config.OnLimited
+	if onLimited == nil {
+		onLimited = func(c *Context) {
+			c.Fail(http.StatusTooManyRequests, "too many requests")
+		}
+	}
+
+	return func(c *Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		result, err := store.Take(c.Rep.Context(), key, rule)
+		if err != nil {
+			if config.OnError != nil {
+				config.OnError(c, err)
+				return
+			}
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.FormatInt(max(ceilSeconds(result.RetryAfter), 1), 10))
+			c.Abort()
+			onLimited(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+const rateLimitShards = 64
+
+// MemoryRateLimitStore keeps limiter state in process. Keys are spread over
+// shards to reduce lock contention, and Take periodically drops keys whose
+// quota has fully recovered, so the store needs no goroutine or Close.
+type MemoryRateLimitStore struct {
+	shards   [rateLimitShards]rateLimitShard
+	now      func() time.Time
+	interval time.Duration
+	// nextSweep is the UnixNano time after which Take sweeps.
+	nextSweep atomic.Int64
+}
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	entries map[string]*rateLimitEntry
+}
+
+type rateLimitEntry struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+	// sliding window
+	start      int64
+	prev, curr float64
+
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns a store sweeping idle keys at most every
+// cleanupInterval, or every minute when it is zero.
+func NewMemoryRateLimitStore(cleanupInterval time.Duration) *MemoryRateLimitStore {
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Minute
+	}
+	s := &MemoryRateLimitStore{now: time.Now, interval: cleanupInterval}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*rateLimitEntry)
+	}
+	return s
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	now := s.now()
+	if next := s.nextSweep.Load(); now.UnixNano() >= next && s.nextSweep.CompareAndSwap(next, now.Add(s.interval).UnixNano()) {
+		s.sweep(now)
+	}
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	e := shard.entries[key]
+	if e == nil || !now.Before(e.expires) {
+		e = &rateLimitEntry{}
+		shard.entries[key] = e
+	}
+	if rule.Algorithm == SlidingWindow {
+		return e.takeSlidingWindow(now, rule), nil
+	}
+	return e.takeTokenBucket(now, rule), nil
+}
+
+// Len reports how many keys the store currently tracks.
+func (s *MemoryRateLimitStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		n += len(shard.entries)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+func (s *MemoryRateLimitStore) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &s.shards[h.Sum32()%rateLimitShards]
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for key, e := range shard.entries {
+			if !now.Before(e.expires) {
+				delete(shard.entries, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
+
+func (e *rateLimitEntry) takeTokenBucket(now time.Time, rule RateLimitRule) RateLimitResult {
+	capacity := float64(rule.capacity())
+	rate := float64(rule.Limit) / float64(rule.Window) // tokens per nanosecond
+	if e.last.IsZero() {
+		e.tokens = capacity
+	} else if elapsed := now.Sub(e.last); elapsed > 0 {
+		e.tokens = math.Min(capacity, e.tokens+float64(elapsed)*rate)
+	}
+	e.last = now
+	result := RateLimitResult{Limit: rule.capacity()}
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+	result.Remaining = int(e.tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - e.tokens) / rate))
+	// Once the bucket is full again the entry is as good as a new one.
+	e.expires = now.Add(result.Reset)
+	return result
+}
+
+func (e *rateLimitEntry) takeSlidingWindow(now time.Time, rule RateLimitRule) RateLimitResult {
+	window := int64(rule.Window)
+	nanos := now.UnixNano()
+	start := nanos - nanos%window
+	switch e.start {
+	case start:
+	case start - window:
+		e.prev, e.curr = e.curr, 0
+	default:
+		e.prev, e.curr = 0, 0
+	}
+	e.start = start
+	elapsed := nanos - start
+	limit := float64(rule.Limit)
+	estimate := e.prev*float64(window-elapsed)/float64(window) + e.curr
+	result := RateLimitResult{Limit: rule.Limit}
+	switch {
+	case estimate+1 <= limit:
+		e.curr++
+		result.Allowed = true
+		result.Remaining = int(limit - estimate - 1)
+	case e.prev > 0 && e.curr+1 <= limit:
+		// Wait for the previous window's weight to decay far enough.
+		result.RetryAfter = time.Duration(math.Ceil(float64(window)*(1-(limit-e.curr-1)/e.prev))) - time.Duration(elapsed)
+	default:
+		result.RetryAfter = time.Duration(window - elapsed)
+	}
+	// Requests of this window keep weighing on the next one, so the quota
+	// is only fully back once that one ends too.
+	switch {
+	case e.curr > 0:
+		result.Reset = time.Duration(2*window - elapsed)
+	case e.prev > 0:
+		result.Reset = time.Duration(window - elapsed)
+	}
+	e.expires = time.Unix(0, start+2*window)
+	return result
+}
@@ -0,0 +1,180 @@
+package Gee
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestRateLimitStore(t *testing.T) (*MemoryRateLimitStore, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	store := NewMemoryRateLimitStore(time.Second)
+	store.now = clock.now
+	return store, clock
+}
+
+func rateLimitRequest(engine *Engine, path string, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitTokenBucketHeaders(t *testing.T) {
+	store, clock := newTestRateLimitStore(t)
+	engine := New()
+	engine.Use(RateLimit(RateLimitConfig{Limit: 2, Window: time.Second, Store: store}))
+	engine.GET("/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := rateLimitRequest(engine, "/ping", "10.0.0.1:1234")
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != remaining {
+			t.Fatalf("request %d: got %d remaining %q", i, rec.Code, rec.Header().Get("RateLimit-Remaining"))
+		}
+		if rec.Header().Get("RateLimit-Limit") != "2" {
+			t.Fatalf("unexpected RateLimit-Limit %q", rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+	rec := rateLimitRequest(engine, "/ping", "10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" || rec.Header().Get("RateLimit-Reset") != "1" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := rateLimitRequest(engine, "/ping", "10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("other clients must have their own bucket, got %d", rec.Code)
+	}
+
+	clock.advance(500 * time.Millisecond)
+	if rec := rateLimitRequest(engine, "/ping", "10.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("expected a refilled token, got %d", rec.Code)
+	}
+	if rec := rateLimitRequest(engine, "/ping", "10.0.0.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 again, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitTokenBucketBurst(t *testing.T) {
+	store, clock := newTestRateLimitStore(t)
+	rule := RateLimitRule{Limit: 1, Window: time.Second, Burst: 3}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if r, _ := store.Take(ctx, "k", rule); !r.Allowed || r.Limit != 3 {
+			t.Fatalf("burst request %d rejected: %+v", i, r)
+		}
+	}
+	r, _ := store.Take(ctx, "k", rule)
+	if r.Allowed || r.RetryAfter != time.Second || r.Reset != 3*time.Second {
+		t.Fatalf("unexpected result after burst %+v", r)
+	}
+	clock.advance(time.Second)
+	if r, _ := store.Take(ctx, "k", rule); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("expected one refilled token, got %+v", r)
+	}
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	store, clock := newTestRateLimitStore(t)
+	rule := RateLimitRule{Algorithm: SlidingWindow, Limit: 4, Window: 10 * time.Second}
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if r, _ := store.Take(ctx, "k", rule); !r.Allowed || r.Remaining != 3-i {
+			t.Fatalf("request %d: %+v", i, r)
+		}
+	}
+	r, _ := store.Take(ctx, "k", rule)
+	if r.Allowed || r.RetryAfter != 10*time.Second || r.Reset != 20*time.Second {
+		t.Fatalf("expected rejection until the window ends, got %+v", r)
+	}
+
+	// Halfway through the next window the previous four count as two.
+	clock.advance(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if r, _ := store.Take(ctx, "k", rule); !r.Allowed {
+			t.Fatalf("request %d in next window rejected: %+v", i, r)
+		}
+	}
+	r, _ = store.Take(ctx, "k", rule)
+	if r.Allowed || r.RetryAfter != 2500*time.Millisecond || r.Reset != 15*time.Second {
+		t.Fatalf("expected rejection until the previous window decays, got %+v", r)
+	}
+}
+
+func TestRateLimitKeyFuncs(t *testing.T) {
+	store, _ := newTestRateLimitStore(t)
+	engine := New()
+	api := engine.Group("/api")
+	api.Use(RateLimit(RateLimitConfig{Limit: 1, Window: time.Minute, Store: store, KeyFunc: KeyByHeader("X-API-Key")}))
+	api.GET("/items", func(c *Context) { c.String(http.StatusOK, "items") })
+	shared := engine.Group("/shared")
+	shared.Use(RateLimit(RateLimitConfig{Limit: 1, Window: time.Minute, Store: store, KeyFunc: KeyByRoute()}))
+	shared.GET("/:id", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("id")) })
+
+	send := func(path string, apiKey string, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if send("/api/items", "a", "10.0.0.1:1") != http.StatusOK || send("/api/items", "a", "10.0.0.2:1") != http.StatusTooManyRequests {
+		t.Fatal("header key must be shared across addresses")
+	}
+	if send("/api/items", "b", "10.0.0.1:1") != http.StatusOK {
+		t.Fatal("each header value needs its own quota")
+	}
+	if send("/shared/1", "", "10.0.0.1:1") != http.StatusOK || send("/shared/2", "", "10.0.0.2:1") != http.StatusTooManyRequests {
+		t.Fatal("route key must be shared by every client of the pattern")
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, RateLimitRule) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store down")
+}
+
+func TestRateLimitStoreErrorFailsOpen(t *testing.T) {
+	engine := New()
+	engine.Use(RateLimit(RateLimitConfig{Limit: 1, Window: time.Second, Store: failingRateLimitStore{}}))
+	engine.GET("/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+	if rec := rateLimitRequest(engine, "/ping", "10.0.0.1:1"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("expected request to pass without headers, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestMemoryRateLimitStoreSweepsIdleKeys(t *testing.T) {
+	store, clock := newTestRateLimitStore(t)
+	ctx := context.Background()
+	_, _ = store.Take(ctx, "bucket", RateLimitRule{Limit: 10, Window: time.Second})
+	_, _ = store.Take(ctx, "window", RateLimitRule{Algorithm: SlidingWindow, Limit: 10, Window: time.Minute})
+	if store.Len() != 2 {
+		t.Fatalf("expected 2 keys, got %d", store.Len())
+	}
+	clock.advance(time.Second)
+	_, _ = store.Take(ctx, "other", RateLimitRule{Limit: 10, Window: time.Hour})
+	if store.Len() != 2 {
+		t.Fatalf("expected the refilled bucket to be dropped on access, got %d keys", store.Len())
+	}
+	clock.advance(time.Second / 2)
+	_, _ = store.Take(ctx, "late", RateLimitRule{Limit: 10, Window: time.Hour})
+	if store.Len() != 3 {
+		t.Fatalf("expected no sweep within the interval, got %d keys", store.Len())
+	}
+	clock.advance(2 * time.Hour)
+	store.sweep(clock.now())
+	if store.Len() != 0 {
+		t.Fatalf("expected every key to be dropped, got %d", store.Len())
+	}
+}
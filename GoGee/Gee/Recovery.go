@@ -28,6 +28,11 @@ func Recovery() HandlerFunc {
 			if err := recover(); err != nil {
 				message := fmt.Sprintf("%v", err)
 				log.Print(trace(message))
+				context.Error(fmt.Errorf("panic: %s", message))
+				context.Abort()
+				if context.Writer.Written() {
+					return
+				}
 				context.JSON(http.StatusInternalServerError, H{"message": "internal server error"})
 			}
 		}()
@@ -0,0 +1,144 @@
+package Gee
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+const noWritten = -1
+
+// ResponseWriter wraps http.ResponseWriter and records what has actually
+// been sent so middleware can inspect it after the handler returns.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+
+	// Status is the code sent to the client, or the one net/http will send
+	// (200) when the handler has not chosen one.
+	Status() int
+	// Size is the number of body bytes written, or -1 before any write.
+	Size() int
+	// Written reports whether the status line and headers have been sent.
+	Written() bool
+	// WriteHeaderNow sends the pending status and headers.
+	WriteHeaderNow()
+	// Unwrap exposes the underlying writer to http.ResponseController.
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status   int
+	size     int
+	hijacked bool
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = noWritten
+	w.hijacked = false
+}
+
+// WriteHeader sends code with the headers; later calls are ignored instead of
+// producing a superfluous WriteHeader warning. Informational codes such as
+// 103 Early Hints go straight through and leave the response unwritten.
+func (w *responseWriter) WriteHeader(code int) {
+	if w.Written() || w.hijacked {
+		return
+	}
+	if informational(code) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.size = 0
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// informational reports whether code is a 1xx response that net/http sends
+// ahead of the final one; 101 Switching Protocols is final.
+func informational(code int) bool {
+	return code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() && !w.hijacked {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+func (w *responseWriter) WriteString(s string) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	w.WriteHeaderNow()
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+// ReadFrom keeps the sendfile fast path of the underlying writer available to
+// io.Copy, which http.FileServer relies on.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	w.WriteHeaderNow()
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.size += int(n)
+	return n, err
+}
+func (w *responseWriter) Status() int {
+	return w.status
+}
+func (w *responseWriter) Size() int {
+	return w.size
+}
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+func (w *responseWriter) Flush() {
+	if w.hijacked {
+		return
+	}
+	w.WriteHeaderNow()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection to the caller. The status is reported as 101
+// Switching Protocols and further writes through w fail with
+// http.ErrHijacked.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+		w.status = http.StatusSwitchingProtocols
+		if w.size == noWritten {
+			w.size = 0
+		}
+	}
+	return conn, rw, err
+}
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,158 @@
+package Gee
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriterTracksDirectWrites(t *testing.T) {
+	engine := New()
+	var status, size int
+	var written bool
+	engine.Use(func(c *Context) {
+		c.Next()
+		status, size, written = c.Writer.Status(), c.Writer.Size(), c.Writer.Written()
+	})
+	engine.GET("/raw", func(c *Context) {
+		c.Writer.WriteHeader(http.StatusAccepted)
+		c.Writer.WriteHeader(http.StatusTeapot)
+		_, _ = c.Writer.Write([]byte("hello"))
+	})
+	engine.GET("/implicit", func(c *Context) {
+		_, _ = c.Writer.Write([]byte("hi"))
+	})
+	engine.GET("/empty", func(c *Context) {})
+
+	cases := []struct {
+		path    string
+		status  int
+		size    int
+		written bool
+	}{
+		{"/raw", http.StatusAccepted, 5, true},
+		{"/implicit", http.StatusOK, 2, true},
+		{"/empty", http.StatusOK, -1, false},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		engine.ServeHTTP(rec, req)
+		if status != tc.status || size != tc.size || written != tc.written {
+			t.Fatalf("%s: got status=%d size=%d written=%v", tc.path, status, size, written)
+		}
+		if rec.Code != tc.status {
+			t.Fatalf("%s: recorder got %d", tc.path, rec.Code)
+		}
+	}
+}
+
+func TestRecoveryAfterHeadersSent(t *testing.T) {
+	engine := New()
+	engine.Use(Recovery())
+	engine.GET("/partial", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/partial", nil)
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Fatalf("recovery must not append to a sent response, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestResponseWriterControllerAndHijack(t *testing.T) {
+	engine := New()
+	engine.GET("/flush", func(c *Context) {
+		_, _ = c.Writer.Write([]byte("chunk"))
+		if err := http.NewResponseController(c.Writer).Flush(); err != nil {
+			t.Errorf("flush through ResponseController failed: %v", err)
+		}
+	})
+	engine.GET("/hijack", func(c *Context) {
+		conn, rw, err := c.Writer.Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 6\r\nConnection: close\r\n\r\nhijack")
+		_ = rw.Flush()
+		if c.Writer.Status() != http.StatusSwitchingProtocols {
+			t.Errorf("expected hijacked status 101, got %d", c.Writer.Status())
+		}
+		if n, err := c.Writer.Write([]byte("late")); n != 0 || err != http.ErrHijacked {
+			t.Errorf("expected ErrHijacked from Write, got %d %v", n, err)
+		}
+		if n, err := io.WriteString(c.Writer, "late"); n != 0 || err != http.ErrHijacked {
+			t.Errorf("expected ErrHijacked from WriteString, got %d %v", n, err)
+		}
+		if c.Writer.Size() != 0 {
+			t.Errorf("expected writes after hijack to leave size 0, got %d", c.Writer.Size())
+		}
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/flush")
+	if err != nil {
+		t.Fatalf("flush request failed: %v", err)
+	}
+	body := new(strings.Builder)
+	_, _ = bufio.NewReader(resp.Body).WriteTo(body)
+	resp.Body.Close()
+	if body.String() != "chunk" {
+		t.Fatalf("unexpected flush body %q", body.String())
+	}
+
+	resp, err = http.Get(srv.URL + "/hijack")
+	if err != nil {
+		t.Fatalf("hijack request failed: %v", err)
+	}
+	body.Reset()
+	_, _ = bufio.NewReader(resp.Body).WriteTo(body)
+	resp.Body.Close()
+	if body.String() != "hijack" {
+		t.Fatalf("unexpected hijack body %q", body.String())
+	}
+}
+
+func TestResponseWriterSendsEarlyHints(t *testing.T) {
+	engine := New()
+	engine.Use(Compress())
+	engine.GET("/hints", func(c *Context) {
+		c.SetHeader("Link", "</app.css>; rel=preload; as=style")
+		c.Writer.WriteHeader(http.StatusEarlyHints)
+		if c.Writer.Written() || c.Writer.Status() != http.StatusOK {
+			t.Error("an interim response must leave the response unwritten")
+		}
+		c.String(http.StatusCreated, "created")
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	var interim []int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			interim = append(interim, code)
+			return nil
+		},
+	}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL+"/hints", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if len(interim) != 1 || interim[0] != http.StatusEarlyHints || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 103 then 201, got %v then %d", interim, resp.StatusCode)
+	}
+}
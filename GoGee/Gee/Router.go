@@ -1,23 +1,59 @@
 package Gee
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
 	"strings"
 )
 
 type Router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
-	routes   []Route
+	roots  map[string]*node
+	routes []Route
+	leaves []*node
+	// host is nil for the default host.
+	host *hostRoute
+	// constraints are the ones registered with Engine.RegisterConstraint.
+	constraints map[string]func(string) bool
 }
 type Route struct {
+	// Host is the pattern passed to Engine.Host, empty for the default host.
+	Host    string `json:"host,omitempty"`
 	Method  string `json:"method"`
 	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"`
+	// Constraints maps the constrained params of Pattern, such as "id" in
+	// "/users/:id<int>", to their constraint.
+	Constraints map[string]string `json:"constraints,omitempty"`
+	// Doc is the metadata attached with RouteRef.Doc, used by OpenAPI.
+	Doc *RouteDoc `json:"-"`
+}
+
+// Param is a single wildcard value captured from the request path.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is backed by a slice so a pooled Context can reuse it across
+// requests without allocating.
+type Params []Param
+
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
 }
 
 func newRouter() *Router {
-	return &Router{handlers: make(map[string]HandlerFunc), roots: make(map[string]*node), routes: make([]Route, 0)}
+	return &Router{roots: make(map[string]*node), routes: make([]Route, 0), leaves: make([]*node, 0)}
 }
 func parsePattern(pattern string) []string {
 	vs := strings.Split(pattern, "/")
@@ -32,19 +68,38 @@ func parsePattern(pattern string) []string {
 	}
 	return parts
 }
-func (router *Router) addRouter(method string, pattern string, handler HandlerFunc) {
-	//fmt.Println(pattern)
-	key := method + "-" + pattern
-	if _, exists := router.handlers[key]; !exists {
-		router.routes = append(router.routes, Route{Method: method, Pattern: pattern})
-	}
-	router.handlers[key] = handler
-	_, ok := router.roots[method]
+func (router *Router) addRouter(method string, pattern string, handlers []HandlerFunc) *node {
+	root, ok := router.roots[method]
 	if !ok {
-		router.roots[method] = &node{}
+		root = &node{}
+		router.roots[method] = root
+	}
+	leaf, names := root.Insert(pattern, router.constraints)
+	if leaf.pattern == pattern {
+		panic(fmt.Sprintf("Gee: route %s %s is already registered", method, pattern))
+	}
+	if leaf.pattern != "" {
+		panic(fmt.Sprintf("Gee: route %s %s conflicts with existing route %s %s", method, pattern, method, leaf.pattern))
+	}
+	leaf.pattern = pattern
+	leaf.paramNames = names
+	leaf.handlers = handlers
+	leaf.chain = handlers
+	route := Route{Method: method, Pattern: pattern}
+	for _, part := range parsePattern(pattern) {
+		if name, constraint := splitParam(part[1:]); part[0] == ':' && constraint != "" {
+			if route.Constraints == nil {
+				route.Constraints = make(map[string]string)
+			}
+			route.Constraints[name] = constraint
+		}
 	}
-	parts := parsePattern(pattern)
-	router.roots[method].Insert(pattern, parts, 0)
+	if router.host != nil {
+		route.Host = router.host.pattern
+	}
+	router.routes = append(router.routes, route)
+	router.leaves = append(router.leaves, leaf)
+	return leaf
 }
 func (router *Router) listRoutes() []Route {
 	routes := make([]Route, len(router.routes))
@@ -52,16 +107,21 @@ func (router *Router) listRoutes() []Route {
 	return routes
 }
 func (router *Router) handle(c *Context, noRoute HandlerFunc, noMethod HandlerFunc) {
-	n, params := router.getRouter(c.Method, c.Path)
+	n, params := router.lookup(c.Method, c.Path, c.Params[:0])
+	c.Params = params
+	if router.host != nil && router.host.params > 0 {
+		c.Params, _ = router.host.match(normalizeHost(c.Rep.Host), c.Params, true)
+	}
 	if n != nil {
-		c.Params = params
-		key := c.Method + "-" + n.pattern
-		if handler, ok := router.handlers[key]; ok {
-			c.handles = append(c.handles, handler)
-		}
+		c.handles = n.chain
+		c.fullPath = n.pattern
+	} else if router.host != nil && !router.pathExists(c.Path) {
+		// Paths a host does not route fall through to the default host.
+		c.engine.router.handle(c, noRoute, noMethod)
+		return
 	} else if router.pathExists(c.Path) {
 		methods := router.allowedMethods(c.Path)
-		c.handles = append(c.handles, func(c *Context) {
+		c.handles = c.engine.fallbackChain(router.host, c.Path, func(c *Context) {
 			if len(methods) > 0 {
 				c.SetHeader("Allow", strings.Join(methods, ", "))
 			}
@@ -73,9 +133,9 @@ func (router *Router) handle(c *Context, noRoute HandlerFunc, noMethod HandlerFu
 		})
 	} else {
 		if noRoute != nil {
-			c.handles = append(c.handles, noRoute)
+			c.handles = c.engine.fallbackChain(router.host, c.Path, noRoute)
 		} else {
-			c.handles = append(c.handles, func(c *Context) {
+			c.handles = c.engine.fallbackChain(router.host, c.Path, func(c *Context) {
 				c.String(http.StatusNotFound, "404 page not found")
 			})
 		}
@@ -103,26 +163,24 @@ func (router *Router) allowedMethods(path string) []string {
 	return methods
 }
 
-func (router *Router) getRouter(method string, pattern string) (*node, map[string]string) {
-	partsOpt := parsePattern(pattern)
-	params := make(map[string]string)
+// lookup matches path for method, reusing params as the backing store for
+// wildcard values. It does not allocate when params has enough capacity.
+func (router *Router) lookup(method string, path string, params Params) (*node, Params) {
 	root, ok := router.roots[method]
 	if !ok {
-		return nil, nil
+		return nil, params[:0]
 	}
-	lastnode := root.Search(partsOpt, 0)
-	if lastnode != nil {
-		parts := parsePattern(lastnode.pattern)
-		for index, part := range parts {
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(partsOpt[index:], "/")
-			}
-			if part[0] == ':' {
-				params[part[1:]] = partsOpt[index]
-			}
-		}
-		return lastnode, params
-	} else {
+	n, params := root.Search(cleanPath(path), params[:0])
+	if n == nil {
+		return nil, params[:0]
+	}
+	return n, n.nameParams(params)
+}
+
+func (router *Router) getRouter(method string, path string) (*node, Params) {
+	n, params := router.lookup(method, path, nil)
+	if n == nil {
 		return nil, nil
 	}
+	return n, params
 }
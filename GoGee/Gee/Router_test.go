@@ -39,11 +39,11 @@ func TestGetRoute(t *testing.T) {
 		t.Fatal("should match /hello/:name")
 	}
 
-	if ps["name"] != "geektutu" {
+	if ps.ByName("name") != "geektutu" {
 		t.Fatal("name should be equal to 'geektutu'")
 	}
 
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps.ByName("name"))
 
 }
 
@@ -75,3 +75,99 @@ func TestListRoutes(t *testing.T) {
 		t.Fatalf("expected 2 routes, got %d", len(routes))
 	}
 }
+
+func TestRoutePriority(t *testing.T) {
+	r := newRouter()
+	r.addRouter("GET", "/users/new", nil)
+	r.addRouter("GET", "/users/:id", nil)
+	r.addRouter("GET", "/users/:name/posts", nil)
+	r.addRouter("GET", "/assets/logo.png", nil)
+	r.addRouter("GET", "/assets/*filepath", nil)
+	r.addRouter("GET", "/files/:name", nil)
+	r.addRouter("GET", "/files/*filepath", nil)
+
+	cases := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/users/new", "/users/new", map[string]string{}},
+		{"/users/newbie", "/users/:id", map[string]string{"id": "newbie"}},
+		{"/users/42", "/users/:id", map[string]string{"id": "42"}},
+		{"/users/tom/posts", "/users/:name/posts", map[string]string{"name": "tom"}},
+		{"/users/new/posts", "/users/:name/posts", map[string]string{"name": "new"}},
+		{"/assets/logo.png", "/assets/logo.png", map[string]string{}},
+		{"/assets/logo.png.bak", "/assets/*filepath", map[string]string{"filepath": "logo.png.bak"}},
+		{"/assets/css/app.css", "/assets/*filepath", map[string]string{"filepath": "css/app.css"}},
+		{"/files/a.txt", "/files/:name", map[string]string{"name": "a.txt"}},
+		{"/files/dir/a.txt", "/files/*filepath", map[string]string{"filepath": "dir/a.txt"}},
+		{"//users//42/", "/users/:id", map[string]string{"id": "42"}},
+	}
+	for _, tc := range cases {
+		n, ps := r.getRouter("GET", tc.path)
+		if n == nil {
+			t.Fatalf("%s: expected match %s, got nil", tc.path, tc.pattern)
+		}
+		if n.pattern != tc.pattern {
+			t.Fatalf("%s: expected match %s, got %s", tc.path, tc.pattern, n.pattern)
+		}
+		got := make(map[string]string, len(ps))
+		for _, p := range ps {
+			got[p.Key] = p.Value
+		}
+		if !reflect.DeepEqual(got, tc.params) {
+			t.Fatalf("%s: expected params %v, got %v", tc.path, tc.params, got)
+		}
+	}
+
+	for _, path := range []string{"/users", "/users/42/comments", "/assets", "/files"} {
+		if n, _ := r.getRouter("GET", path); n != nil {
+			t.Fatalf("%s: expected no match, got %s", path, n.pattern)
+		}
+	}
+}
+
+func TestRouteRegistrationOrderIndependent(t *testing.T) {
+	patterns := []string{"/assets/*filepath", "/assets/logo.png", "/users/:name/posts", "/users/:id"}
+	forward, backward := newRouter(), newRouter()
+	for i := range patterns {
+		forward.addRouter("GET", patterns[i], nil)
+		backward.addRouter("GET", patterns[len(patterns)-1-i], nil)
+	}
+	for _, path := range []string{"/assets/logo.png", "/assets/a/b", "/users/1", "/users/1/posts"} {
+		n1, _ := forward.getRouter("GET", path)
+		n2, _ := backward.getRouter("GET", path)
+		if n1 == nil || n2 == nil || n1.pattern != n2.pattern {
+			t.Fatalf("%s: registration order changed the match", path)
+		}
+	}
+}
+
+func TestRouteConflictPanics(t *testing.T) {
+	cases := [][2]string{
+		{"/users/:id", "/users/:name"},
+		{"/files/*a", "/files/*b"},
+		{"/ping", "/ping"},
+		{"/ping/", "/ping"},
+		{"/a/:x/:x", ""},
+		{"/a/:", ""},
+	}
+	for _, tc := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic registering %v", tc)
+				}
+			}()
+			r := newRouter()
+			r.addRouter("GET", tc[0], nil)
+			if tc[1] != "" {
+				r.addRouter("GET", tc[1], nil)
+			}
+		}()
+	}
+
+	r := newRouter()
+	r.addRouter("GET", "/users/:id", nil)
+	r.addRouter("POST", "/users/:name", nil)
+}
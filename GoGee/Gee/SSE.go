@@ -0,0 +1,141 @@
+package Gee
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEvent is one Server-Sent Event. Data that is not a string or []byte is
+// encoded as JSON; multi-line data is split over several data fields.
+type SSEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+var sseFieldReplacer = strings.NewReplacer("\r\n", "", "\n", "", "\r", "")
+
+func (ev SSEvent) encode(w io.Writer) error {
+	var b strings.Builder
+	if ev.ID != "" {
+		b.WriteString("id: " + sseFieldReplacer.Replace(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		b.WriteString("event: " + sseFieldReplacer.Replace(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(raw)
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// LastEventID returns the id a reconnecting EventSource resumes from.
+func (c *Context) LastEventID() string {
+	return c.Rep.Header.Get("Last-Event-ID")
+}
+
+// setStreamHeaders prepares an unbuffered, uncached stream, keeping a
+// Content-Type the handler already chose. Connection headers are left to
+// net/http, which forbids them on HTTP/2.
+func (c *Context) setStreamHeaders() {
+	if c.Writer.Written() {
+		return
+	}
+	header := c.Writer.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/event-stream")
+	}
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+}
+
+// SendEvent writes ev and flushes it to the client.
+func (c *Context) SendEvent(ev SSEvent) error {
+	c.setStreamHeaders()
+	if err := ev.encode(c.Writer); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+func (c *Context) SSEvent(event string, data interface{}) error {
+	return c.SendEvent(SSEvent{Event: event, Data: data})
+}
+
+// Stream calls step until it returns false or the client goes away, flushing
+// after every call. It reports whether the client disconnected. The response
+// is sent as text/event-stream unless Content-Type was set before.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	c.setStreamHeaders()
+	done := c.Rep.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+		}
+		keepOpen := step(c.Writer)
+		c.Writer.Flush()
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
+// StreamEvents sends every event received from events until the channel is
+// closed or the client disconnects, writing a comment line every heartbeat
+// so proxies keep the connection open. A zero heartbeat disables it. It
+// reports whether the client disconnected.
+func (c *Context) StreamEvents(events <-chan SSEvent, heartbeat time.Duration) bool {
+	c.setStreamHeaders()
+	c.Writer.Flush()
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := c.Rep.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case <-tick:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return true
+			}
+			c.Writer.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.SendEvent(ev); err != nil {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package Gee
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEventFormat(t *testing.T) {
+	engine := New()
+	engine.GET("/events", func(c *Context) {
+		_ = c.SendEvent(SSEvent{ID: "7", Event: "progress", Data: H{"done": 1}, Retry: time.Second})
+		_ = c.SSEvent("log", "line1\nline2")
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/events", nil)
+	engine.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+	if rec.Header().Get("Cache-Control") != "no-cache" || rec.Header().Get("Connection") != "" || !rec.Flushed {
+		t.Fatal("expected no-cache header and a flushed response")
+	}
+	expected := "id: 7\nevent: progress\nretry: 1000\ndata: {\"done\":1}\n\n" +
+		"event: log\ndata: line1\ndata: line2\n\n"
+	if rec.Body.String() != expected {
+		t.Fatalf("unexpected event stream:\n%q", rec.Body.String())
+	}
+}
+
+func TestStreamStopsOnDisconnect(t *testing.T) {
+	engine := New()
+	disconnected := make(chan bool, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	engine.GET("/stream", func(c *Context) {
+		n := 0
+		disconnected <- c.Stream(func(w io.Writer) bool {
+			n++
+			_, _ = io.WriteString(w, "tick\n")
+			if n == 3 {
+				cancel()
+			}
+			return true
+		})
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/stream", nil)
+	engine.ServeHTTP(rec, req)
+	if !<-disconnected {
+		t.Fatal("expected Stream to report the disconnect")
+	}
+	if rec.Body.String() != "tick\ntick\ntick\n" {
+		t.Fatalf("unexpected stream body %q", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" || rec.Header().Get("Cache-Control") != "no-cache" || rec.Header().Get("Connection") != "" {
+		t.Fatalf("unexpected stream headers %v", rec.Header())
+	}
+}
+
+func TestStreamEventsHeartbeatAndLastEventID(t *testing.T) {
+	engine := New()
+	engine.GET("/jobs", func(c *Context) {
+		events := make(chan SSEvent)
+		go func() {
+			defer close(events)
+			time.Sleep(60 * time.Millisecond)
+			events <- SSEvent{ID: "2", Data: "resumed from " + c.LastEventID()}
+		}()
+		c.StreamEvents(events, 20*time.Millisecond)
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/jobs", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(bufio.NewReader(resp.Body))
+	if !strings.Contains(string(body), ": heartbeat\n\n") {
+		t.Fatalf("expected heartbeat comment, got %q", body)
+	}
+	if !strings.HasSuffix(string(body), "id: 2\ndata: resumed from 1\n\n") {
+		t.Fatalf("expected resumed event, got %q", body)
+	}
+}
@@ -0,0 +1,112 @@
+package Gee
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+)
+
+// newServer builds an http.Server for addr using the engine's timeouts and
+// tracks it so Shutdown can drain it.
+func (engine *Engine) newServer(addr string) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadHeaderTimeout: engine.ReadHeaderTimeout,
+		ReadTimeout:       engine.ReadTimeout,
+		WriteTimeout:      engine.WriteTimeout,
+		IdleTimeout:       engine.IdleTimeout,
+		MaxHeaderBytes:    engine.MaxHeaderBytes,
+	}
+	engine.serverMu.Lock()
+	defer engine.serverMu.Unlock()
+	if engine.shutdown {
+		return nil, http.ErrServerClosed
+	}
+	for _, hook := range engine.onShutdown {
+		srv.RegisterOnShutdown(hook)
+	}
+	engine.servers = append(engine.servers, srv)
+	return srv, nil
+}
+
+// serveResult hides http.ErrServerClosed, which only signals that Shutdown
+// was called.
+func serveResult(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Run listens on addr and serves HTTP until Shutdown is called, in which
+// case it returns nil.
+func (engine *Engine) Run(addr string) error {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return serveResult(err)
+	}
+	return serveResult(srv.ListenAndServe())
+}
+
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) error {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return serveResult(err)
+	}
+	return serveResult(srv.ListenAndServeTLS(certFile, keyFile))
+}
+
+func (engine *Engine) RunListener(listener net.Listener) error {
+	srv, err := engine.newServer(listener.Addr().String())
+	if err != nil {
+		_ = listener.Close()
+		return serveResult(err)
+	}
+	return serveResult(srv.Serve(listener))
+}
+
+// RunUnix serves on a unix socket at path, replacing a stale socket file and
+// removing it once the server stops.
+func (engine *Engine) RunUnix(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	return engine.RunListener(listener)
+}
+
+// OnShutdown registers hook to run when Shutdown is called, for servers
+// started before and after the registration.
+func (engine *Engine) OnShutdown(hook func()) {
+	engine.serverMu.Lock()
+	defer engine.serverMu.Unlock()
+	engine.onShutdown = append(engine.onShutdown, hook)
+	for _, srv := range engine.servers {
+		srv.RegisterOnShutdown(hook)
+	}
+}
+
+// Shutdown stops every server started by Run* from accepting connections and
+// waits for in-flight requests to finish or ctx to expire.
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.serverMu.Lock()
+	engine.shutdown = true
+	servers := engine.servers
+	engine.servers = nil
+	engine.serverMu.Unlock()
+
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
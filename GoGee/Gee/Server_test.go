@@ -0,0 +1,105 @@
+package Gee
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEngineShutdownDrainsInFlight(t *testing.T) {
+	engine := New()
+	engine.ReadHeaderTimeout = time.Second
+	started := make(chan struct{})
+	engine.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	var hooked atomic.Bool
+	engine.OnShutdown(func() { hooked.Store(true) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- engine.RunListener(listener) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resp := make(chan result, 1)
+	go func() {
+		r, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			resp <- result{err: err}
+			return
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		resp <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if r := <-resp; r.err != nil || r.body != "done" {
+		t.Fatalf("in-flight request was not drained: %+v", r)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("expected RunListener to return nil after shutdown, got %v", err)
+	}
+	if !hooked.Load() {
+		t.Fatal("expected OnShutdown hook to run")
+	}
+	if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Fatal("expected listener to be closed after shutdown")
+	}
+}
+
+func TestEngineRunUnix(t *testing.T) {
+	engine := New()
+	engine.GET("/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+	socket := filepath.Join(t.TempDir(), "gee.sock")
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- engine.RunUnix(socket) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	var body []byte
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r, err := client.Get("http://unix/ping")
+		if err == nil {
+			body, _ = io.ReadAll(r.Body)
+			r.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unix socket never came up: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if string(body) != "pong" {
+		t.Fatalf("unexpected body %s", body)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("expected RunUnix to return nil, got %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package Gee
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrSessionSaveTooLate = errors.New("Gee: session saved after the response headers were sent")
+
+// Session holds the values of one client session. Values round-trip
+// through encoding/json, so numbers read back as float64.
+type Session struct {
+	id      string
+	values  map[string]interface{}
+	isNew   bool
+	renewed string
+	store   SessionStore
+	c       *Context
+}
+
+// NewSession creates a session for SessionStore implementations. A nil
+// values map starts a new, empty session.
+func NewSession(id string, values map[string]interface{}) *Session {
+	if values == nil {
+		return &Session{id: id, values: make(map[string]interface{}), isNew: true}
+	}
+	return &Session{id: id, values: values}
+}
+
+// ID is the server-side identifier of the session; it is empty for
+// sessions kept entirely in the cookie.
+func (s *Session) ID() string {
+	return s.id
+}
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+func (s *Session) Get(key string) interface{} {
+	return s.values[key]
+}
+func (s *Session) Set(key string, value interface{}) {
+	s.values[key] = value
+}
+func (s *Session) Delete(key string) {
+	delete(s.values, key)
+}
+
+// Clear removes every value; saving an empty session deletes it.
+func (s *Session) Clear() {
+	clear(s.values)
+}
+
+// Values exposes the session values to SessionStore implementations.
+func (s *Session) Values() map[string]interface{} {
+	return s.values
+}
+
+// RenewID gives the session a new identifier on the next Save. Call it
+// after login to defeat session fixation.
+func (s *Session) RenewID() {
+	if s.renewed == "" {
+		s.renewed = s.id
+	}
+	s.id = ""
+}
+
+// Save persists the session and sets its cookie. It must be called before
+// the response body is written.
+func (s *Session) Save() error {
+	if s.c.Writer.Written() {
+		return ErrSessionSaveTooLate
+	}
+	if err := s.store.Save(s.c, s); err != nil {
+		return err
+	}
+	s.isNew = false
+	return nil
+}
+
+// SessionStore loads and saves sessions for the Sessions middleware.
+// Load returns a new session when the request carries none.
+type SessionStore interface {
+	Load(c *Context) (*Session, error)
+	Save(c *Context, s *Session) error
+}
+
+// SessionOptions configures the session cookie. Cookies are HttpOnly
+// unless ScriptAccess is set; an empty Name, Path or SameSite takes the
+// value of DefaultSessionOptions.
+type SessionOptions struct {
+	Name   string
+	Path   string
+	Domain string
+	// MaxAge is the session lifetime. Zero makes a browser-session cookie,
+	// kept for a day by server-side stores.
+	MaxAge time.Duration
+	Secure bool
+	// ScriptAccess leaves out HttpOnly so scripts can read the cookie.
+	ScriptAccess bool
+	SameSite     http.SameSite
+}
+
+var DefaultSessionOptions = SessionOptions{
+	Name:     "gee_session",
+	Path:     "/",
+	MaxAge:   24 * time.Hour,
+	SameSite: http.SameSiteLaxMode,
+}
+
+func (o *SessionOptions) cookie(value string, delete bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     o.Name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		Secure:   o.Secure,
+		HttpOnly: !o.ScriptAccess,
+		SameSite: o.SameSite,
+	}
+	switch {
+	case delete:
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+	case o.MaxAge > 0:
+		cookie.MaxAge = int(o.MaxAge / time.Second)
+		cookie.Expires = time.Now().Add(o.MaxAge)
+	}
+	return cookie
+}
+
+func (o SessionOptions) withDefaults() SessionOptions {
+	if o.Name == "" {
+		o.Name = DefaultSessionOptions.Name
+	}
+	if o.Path == "" {
+		o.Path = DefaultSessionOptions.Path
+	}
+	if o.SameSite == 0 {
+		o.SameSite = DefaultSessionOptions.SameSite
+	}
+	return o
+}
+
+// Sessions makes c.Session available to the rest of the chain. The session
+// is loaded on first use and only persisted by Session.Save.
+func Sessions(store SessionStore) HandlerFunc {
+	if store == nil {
+		panic("Gee: Sessions needs a store")
+	}
+	return func(c *Context) {
+		c.sessionStore = store
+		c.Next()
+	}
+}
+
+// Session returns the session of the request. A session that fails to
+// load is replaced by a new one and the error is recorded with c.Error.
+func (c *Context) Session() *Session {
+	if c.session != nil {
+		return c.session
+	}
+	if c.sessionStore == nil {
+		panic("Gee: c.Session needs the Sessions middleware")
+	}
+	s, err := c.sessionStore.Load(c)
+	if err != nil || s == nil {
+		c.Error(err)
+		s = NewSession("", nil)
+	}
+	s.store, s.c = c.sessionStore, c
+	c.session = s
+	return s
+}
+
+// CookieStore keeps the whole session in a cookie protected by a codec.
+// Browsers cap cookies at about 4KB, so it suits small sessions.
+type CookieStore struct {
+	codec   CookieCodec
+	options SessionOptions
+}
+
+func NewCookieStore(codec CookieCodec, options SessionOptions) *CookieStore {
+	if codec == nil {
+		panic("Gee: cookie store needs a codec")
+	}
+	return &CookieStore{codec: codec, options: options.withDefaults()}
+}
+
+func (store *CookieStore) Load(c *Context) (*Session, error) {
+	value, err := c.Cookie(store.options.Name)
+	if err != nil {
+		return NewSession("", nil), nil
+	}
+	data, err := store.codec.Decode(store.options.Name, value)
+	if err != nil {
+		return NewSession("", nil), nil
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return NewSession("", nil), nil
+	}
+	return NewSession("", values), nil
+}
+
+func (store *CookieStore) Save(c *Context, s *Session) error {
+	if len(s.values) == 0 {
+		if !s.isNew {
+			c.SetCookie(store.options.cookie("", true))
+		}
+		return nil
+	}
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	value, err := store.codec.Encode(store.options.Name, data)
+	if err != nil {
+		return err
+	}
+	if len(value) > 4000 {
+		return fmt.Errorf("Gee: session cookie of %d bytes is too large", len(value))
+	}
+	c.SetCookie(store.options.cookie(value, false))
+	return nil
+}
+
+// SessionBackend stores serialized sessions by ID for ServerStore.
+// Implementations can sit on Redis, a database or memory; Load returns nil
+// data for unknown or expired IDs.
+type SessionBackend interface {
+	Load(ctx context.Context, id string) ([]byte, error)
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ServerStore keeps session values in a SessionBackend; the cookie only
+// carries a random session ID.
+type ServerStore struct {
+	backend SessionBackend
+	options SessionOptions
+}
+
+func NewServerStore(backend SessionBackend, options SessionOptions) *ServerStore {
+	if backend == nil {
+		panic("Gee: server session store needs a backend")
+	}
+	return &ServerStore{backend: backend, options: options.withDefaults()}
+}
+
+// NewMemoryStore keeps sessions in process memory, for tests and single
+// instance deployments.
+func NewMemoryStore(options SessionOptions) *ServerStore {
+	return NewServerStore(NewMemorySessionBackend(), options)
+}
+
+func (store *ServerStore) ttl() time.Duration {
+	if store.options.MaxAge > 0 {
+		return store.options.MaxAge
+	}
+	return 24 * time.Hour
+}
+
+func (store *ServerStore) Load(c *Context) (*Session, error) {
+	id, err := c.Cookie(store.options.Name)
+	if err != nil || id == "" {
+		return NewSession("", nil), nil
+	}
+	data, err := store.backend.Load(c.Rep.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return NewSession("", nil), nil
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return NewSession("", nil), nil
+	}
+	return NewSession(id, values), nil
+}
+
+func (store *ServerStore) Save(c *Context, s *Session) error {
+	ctx := c.Rep.Context()
+	if s.renewed != "" {
+		if err := store.backend.Delete(ctx, s.renewed); err != nil {
+			return err
+		}
+		s.renewed = ""
+	}
+	if len(s.values) == 0 {
+		if s.id != "" {
+			if err := store.backend.Delete(ctx, s.id); err != nil {
+				return err
+			}
+			s.id = ""
+		}
+		if !s.isNew {
+			c.SetCookie(store.options.cookie("", true))
+		}
+		return nil
+	}
+	if s.id == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		s.id = id
+	}
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	if err := store.backend.Save(ctx, s.id, data, store.ttl()); err != nil {
+		return err
+	}
+	c.SetCookie(store.options.cookie(s.id, false))
+	return nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// MemorySessionBackend is an in-process SessionBackend. Expired sessions
+// are dropped when read and swept periodically on writes.
+type MemorySessionBackend struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	writes   int
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewMemorySessionBackend() *MemorySessionBackend {
+	return &MemorySessionBackend{sessions: make(map[string]memorySession)}
+}
+
+func (b *MemorySessionBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if !time.Now().Before(s.expires) {
+		delete(b.sessions, id)
+		return nil, nil
+	}
+	return s.data, nil
+}
+func (b *MemorySessionBackend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+	if b.writes++; b.writes%1024 == 0 {
+		for key, s := range b.sessions {
+			if !now.Before(s.expires) {
+				delete(b.sessions, key)
+			}
+		}
+	}
+	return nil
+}
+func (b *MemorySessionBackend) Delete(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, id)
+	return nil
+}
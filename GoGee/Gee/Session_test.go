@@ -0,0 +1,133 @@
+package Gee
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sessionClient replays the cookies set by previous responses.
+type sessionClient struct {
+	engine  *Engine
+	cookies map[string]*http.Cookie
+}
+
+func (sc *sessionClient) get(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, cookie := range sc.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	sc.engine.ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(sc.cookies, cookie.Name)
+			continue
+		}
+		sc.cookies[cookie.Name] = cookie
+	}
+	return rec
+}
+
+func newSessionEngine(store SessionStore) *Engine {
+	engine := New()
+	engine.Use(Sessions(store))
+	engine.GET("/login", func(c *Context) {
+		s := c.Session()
+		s.RenewID()
+		s.Set("user", c.Query("user"))
+		s.Set("visits", 0)
+		if err := s.Save(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "welcome")
+	})
+	engine.GET("/me", func(c *Context) {
+		s := c.Session()
+		if s.IsNew() {
+			c.Fail(http.StatusUnauthorized, "login first")
+			return
+		}
+		s.Set("visits", s.Get("visits").(float64)+1)
+		_ = s.Save()
+		c.String(http.StatusOK, "%s %v", s.Get("user"), s.Get("visits"))
+	})
+	engine.GET("/logout", func(c *Context) {
+		s := c.Session()
+		s.Clear()
+		_ = s.Save()
+		c.String(http.StatusOK, "bye")
+	})
+	engine.GET("/late", func(c *Context) {
+		c.String(http.StatusOK, "body first")
+		if err := c.Session().Save(); err != ErrSessionSaveTooLate {
+			panic("expected ErrSessionSaveTooLate")
+		}
+	})
+	return engine
+}
+
+func TestSessionStores(t *testing.T) {
+	codec, err := NewEncryptedCookieCodec(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]SessionStore{
+		"cookie": NewCookieStore(codec, DefaultSessionOptions),
+		"memory": NewMemoryStore(DefaultSessionOptions),
+	}
+	for name, store := range stores {
+		client := &sessionClient{engine: newSessionEngine(store), cookies: make(map[string]*http.Cookie)}
+		if rec := client.get(t, "/me"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401 before login, got %d", name, rec.Code)
+		}
+		rec := client.get(t, "/login?user=alice")
+		setCookie := rec.Header().Get("Set-Cookie")
+		if !strings.HasPrefix(setCookie, "gee_session=") || !strings.Contains(setCookie, "HttpOnly; SameSite=Lax") || !strings.Contains(setCookie, "Max-Age=86400") {
+			t.Fatalf("%s: unexpected session cookie %q", name, setCookie)
+		}
+		for i := 1; i <= 2; i++ {
+			if rec := client.get(t, "/me"); rec.Code != http.StatusOK || rec.Body.String() != "alice "+string(rune('0'+i)) {
+				t.Fatalf("%s: visit %d got %d %q", name, i, rec.Code, rec.Body.String())
+			}
+		}
+		client.get(t, "/late")
+		if rec := client.get(t, "/logout"); !strings.Contains(rec.Header().Get("Set-Cookie"), "Max-Age=0") {
+			t.Fatalf("%s: logout must expire the cookie, got %q", name, rec.Header().Get("Set-Cookie"))
+		}
+		if rec := client.get(t, "/me"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401 after logout, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestSessionOptionsDefaults(t *testing.T) {
+	options := SessionOptions{}.withDefaults()
+	if got := options.cookie("v", false).String(); got != "gee_session=v; Path=/; HttpOnly; SameSite=Lax" {
+		t.Fatalf("zero options must give a safe cookie, got %q", got)
+	}
+	options = SessionOptions{Name: "sid", ScriptAccess: true, SameSite: http.SameSiteStrictMode}.withDefaults()
+	if got := options.cookie("v", false).String(); got != "sid=v; Path=/; SameSite=Strict" {
+		t.Fatalf("explicit options must be kept, got %q", got)
+	}
+}
+
+func TestMemoryStoreRenewsID(t *testing.T) {
+	store := NewMemoryStore(DefaultSessionOptions)
+	client := &sessionClient{engine: newSessionEngine(store), cookies: make(map[string]*http.Cookie)}
+	client.get(t, "/login?user=alice")
+	first := client.cookies["gee_session"].Value
+	client.get(t, "/login?user=bob")
+	second := client.cookies["gee_session"].Value
+	if first == second {
+		t.Fatal("login must issue a new session ID")
+	}
+	client.cookies["gee_session"].Value = first
+	if rec := client.get(t, "/me"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("the replaced session must be gone, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,293 @@
+package Gee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticConfig configures StaticWithConfig.
+type StaticConfig struct {
+	FS fs.FS
+	// CacheControl maps extensions such as ".js" to Cache-Control values;
+	// DefaultCacheControl applies to the other files. Nothing is sent when
+	// both are empty.
+	CacheControl        map[string]string
+	DefaultCacheControl string
+	// Precompressed serves a "name.br" or "name.gz" sibling in place of
+	// name when the client accepts that encoding.
+	Precompressed bool
+	// SPA serves Index for missing paths without an extension, so a
+	// client-side router can handle them. Missing assets still get 404.
+	SPA bool
+	// Index is served for directories; it defaults to "index.html".
+	Index string
+	// Browse lists directories that have no index file.
+	Browse bool
+}
+
+// Static serves the files under the root directory below relativePath.
+func (routerGroup *RouterGroup) Static(relativePath string, root string) {
+	routerGroup.StaticWithConfig(relativePath, StaticConfig{FS: os.DirFS(root)})
+}
+
+// StaticFS serves fsys, such as an embed.FS, below relativePath. Use
+// fs.Sub to serve a subdirectory of an embedded tree.
+func (routerGroup *RouterGroup) StaticFS(relativePath string, fsys fs.FS) {
+	routerGroup.StaticWithConfig(relativePath, StaticConfig{FS: fsys})
+}
+
+// StaticWithConfig serves config.FS below relativePath. Its HEAD routes
+// and the bare relativePath route are skipped when already registered.
+func (routerGroup *RouterGroup) StaticWithConfig(relativePath string, config StaticConfig) {
+	if config.FS == nil {
+		panic("Gee: static files need a file system")
+	}
+	s := newStaticServer(config)
+	handler := func(c *Context) {
+		s.serve(c, c.Param("filepath"))
+	}
+	pattern := path.Join(relativePath, "/*filepath")
+	routerGroup.GET(pattern, handler)
+	routerGroup.addImplicitRoute(http.MethodHead, pattern, handler)
+	// The bare prefix redirects to the root directory.
+	if root := path.Join(routerGroup.prefix, relativePath); root != "/" {
+		routerGroup.addImplicitRoute(http.MethodGet, relativePath, handler)
+		routerGroup.addImplicitRoute(http.MethodHead, relativePath, handler)
+	}
+}
+
+// addImplicitRoute registers a route the caller did not ask for by name,
+// leaving an existing route with the same method and pattern in place.
+func (routerGroup *RouterGroup) addImplicitRoute(method string, comp string, handler HandlerFunc) {
+	pattern := joinRoutePath(routerGroup.prefix, comp)
+	for _, route := range routerGroup.router().routes {
+		if route.Method == method && route.Pattern == pattern {
+			return
+		}
+	}
+	routerGroup.addRoute(method, comp, []HandlerFunc{handler})
+}
+
+// StaticFile serves the file at filePath on relativePath.
+func (routerGroup *RouterGroup) StaticFile(relativePath string, filePath string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("Gee: StaticFile path cannot have parameters")
+	}
+	s := newStaticServer(StaticConfig{FS: os.DirFS(filepath.Dir(filePath))})
+	name := filepath.Base(filePath)
+	handler := func(c *Context) {
+		s.serveFile(c, name)
+	}
+	routerGroup.GET(relativePath, handler)
+	routerGroup.addImplicitRoute(http.MethodHead, relativePath, handler)
+}
+
+func (engine *Engine) Static(relativePath string, root string) {
+	engine.routerGroup.Static(relativePath, root)
+}
+func (engine *Engine) StaticFS(relativePath string, fsys fs.FS) {
+	engine.routerGroup.StaticFS(relativePath, fsys)
+}
+func (engine *Engine) StaticWithConfig(relativePath string, config StaticConfig) {
+	engine.routerGroup.StaticWithConfig(relativePath, config)
+}
+func (engine *Engine) StaticFile(relativePath string, filePath string) {
+	engine.routerGroup.StaticFile(relativePath, filePath)
+}
+
+type staticServer struct {
+	StaticConfig
+	// etags caches strong ETags by file name, invalidated by size and
+	// modification time.
+	etags sync.Map
+}
+
+type staticETag struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+func newStaticServer(config StaticConfig) *staticServer {
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+	return &staticServer{StaticConfig: config}
+}
+
+func (s *staticServer) serve(c *Context, name string) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	info, err := fs.Stat(s.FS, name)
+	if err != nil {
+		if s.SPA && path.Ext(name) == "" {
+			s.serveFile(c, s.Index)
+			return
+		}
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if !info.IsDir() {
+		s.serveFile(c, name)
+		return
+	}
+	// Relative links in index pages need the trailing slash.
+	if !strings.HasSuffix(c.Rep.URL.Path, "/") {
+		target := path.Base(c.Rep.URL.Path) + "/"
+		if c.Rep.URL.RawQuery != "" {
+			target += "?" + c.Rep.URL.RawQuery
+		}
+		http.Redirect(c.Writer, c.Rep, target, http.StatusMovedPermanently)
+		return
+	}
+	index := path.Join(name, s.Index)
+	if _, err := fs.Stat(s.FS, index); err == nil {
+		s.serveFile(c, index)
+		return
+	}
+	if s.Browse {
+		s.list(c, name)
+		return
+	}
+	c.Status(http.StatusNotFound)
+}
+
+func (s *staticServer) serveFile(c *Context, name string) {
+	header := c.Writer.Header()
+	served := name
+	if s.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		accept := c.Rep.Header.Get("Accept-Encoding")
+		for _, enc := range [...]struct{ name, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+			if !acceptsEncoding(accept, enc.name) {
+				continue
+			}
+			if info, err := fs.Stat(s.FS, name+enc.ext); err == nil && !info.IsDir() {
+				served = name + enc.ext
+				header.Set("Content-Encoding", enc.name)
+				break
+			}
+		}
+	}
+	f, err := s.FS.Open(served)
+	if err != nil {
+		header.Del("Content-Encoding")
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		header.Del("Content-Encoding")
+		c.Status(http.StatusNotFound)
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			header.Del("Content-Encoding")
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	etag, err := s.etag(served, info, content)
+	if err != nil {
+		header.Del("Content-Encoding")
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	header.Set("ETag", etag)
+	if header.Get("Content-Type") == "" {
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			header.Set("Content-Type", ctype)
+		} else if served != name {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+	}
+	if cc := s.cacheControl(name); cc != "" {
+		header.Set("Cache-Control", cc)
+	}
+	http.ServeContent(c.Writer, c.Rep, name, info.ModTime(), content)
+}
+
+func (s *staticServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if cached, ok := s.etags.Load(name); ok {
+		e := cached.(staticETag)
+		if e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+			return e.etag, nil
+		}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:18]) + `"`
+	s.etags.Store(name, staticETag{size: info.Size(), modTime: info.ModTime(), etag: etag})
+	return etag, nil
+}
+
+func (s *staticServer) cacheControl(name string) string {
+	if cc, ok := s.CacheControl[strings.ToLower(path.Ext(name))]; ok {
+		return cc
+	}
+	return s.DefaultCacheControl
+}
+
+func (s *staticServer) list(c *Context, name string) {
+	entries, err := fs.ReadDir(s.FS, name)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	buf.WriteString("</pre>\n")
+	c.HTML(http.StatusOK, buf.String())
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding
+// with a non-zero q-value.
+func acceptsEncoding(header string, coding string) bool {
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(v, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
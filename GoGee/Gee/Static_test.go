@@ -0,0 +1,169 @@
+package Gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func staticRequest(engine *Engine, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStaticFS(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html":        {Data: []byte("<h1>app</h1>")},
+		"app.js":            {Data: []byte("console.log('app')")},
+		"app.js.gz":         {Data: []byte("gzip bytes")},
+		"app.js.br":         {Data: []byte("brotli bytes")},
+		"docs/guide.txt":    {Data: []byte("guide")},
+		"docs/api/spec.txt": {Data: []byte("spec")},
+	}
+	engine := New()
+	engine.StaticFS("/plain", assets)
+	engine.StaticWithConfig("/app", StaticConfig{
+		FS:                  assets,
+		CacheControl:        map[string]string{".js": "public, max-age=31536000, immutable"},
+		DefaultCacheControl: "no-cache",
+		Precompressed:       true,
+		SPA:                 true,
+	})
+	engine.StaticWithConfig("/browse", StaticConfig{FS: assets, Browse: true})
+
+	rec := staticRequest(engine, http.MethodGet, "/plain/app.js", nil)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log('app')" || !strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/`) {
+		t.Fatalf("unexpected asset response %d %q etag %q", rec.Code, rec.Body.String(), etag)
+	}
+	if ctype := rec.Header().Get("Content-Type"); !strings.Contains(ctype, "javascript") {
+		t.Fatalf("unexpected content type %q", ctype)
+	}
+	if rec.Header().Get("Cache-Control") != "" || rec.Header().Get("Vary") != "" {
+		t.Fatalf("plain StaticFS must not add caching headers, got %v", rec.Header())
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/plain/app.js", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+	if rec := staticRequest(engine, http.MethodHead, "/plain/app.js", nil); rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "18" {
+		t.Fatalf("unexpected HEAD response %d %v", rec.Code, rec.Header())
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/plain/docs/", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("directory listing must be off by default, got %d", rec.Code)
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/plain/missing", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without SPA mode, got %d", rec.Code)
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/plain", nil); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/plain/" {
+		t.Fatalf("expected a redirect to the directory, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/plain/", nil); rec.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("expected the index page, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	encodings := []struct {
+		accept, encoding, body string
+	}{
+		{"gzip, br", "br", "brotli bytes"},
+		{"gzip, br;q=0", "gzip", "gzip bytes"},
+		{"", "", "console.log('app')"},
+	}
+	for _, tc := range encodings {
+		rec := staticRequest(engine, http.MethodGet, "/app/app.js", http.Header{"Accept-Encoding": {tc.accept}})
+		if rec.Header().Get("Content-Encoding") != tc.encoding || rec.Body.String() != tc.body {
+			t.Fatalf("Accept-Encoding %q: got %q %q", tc.accept, rec.Header().Get("Content-Encoding"), rec.Body.String())
+		}
+		if !strings.Contains(rec.Header().Get("Content-Type"), "javascript") || rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("Accept-Encoding %q: unexpected headers %v", tc.accept, rec.Header())
+		}
+		if rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+			t.Fatalf("unexpected Cache-Control %q", rec.Header().Get("Cache-Control"))
+		}
+		if tc.encoding != "" && rec.Header().Get("ETag") == etag {
+			t.Fatalf("encoded variants need their own ETag")
+		}
+	}
+
+	rec = staticRequest(engine, http.MethodGet, "/app/users/42", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "<h1>app</h1>" || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("expected the SPA fallback, got %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/app/missing.css", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing assets must not fall back to the index, got %d", rec.Code)
+	}
+
+	rec = staticRequest(engine, http.MethodGet, "/browse/docs/", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<a href="api/">api/</a>`) || !strings.Contains(rec.Body.String(), `<a href="guide.txt">guide.txt</a>`) {
+		t.Fatalf("unexpected listing %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticDirAndFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "public")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "..", "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := New()
+	group := engine.Group("/v1")
+	group.Static("/files", dir)
+	engine.StaticFile("/robots.txt", filepath.Join(dir, "robots.txt"))
+
+	if rec := staticRequest(engine, http.MethodGet, "/v1/files/robots.txt", nil); rec.Code != http.StatusOK || rec.Body.String() != "User-agent: *" {
+		t.Fatalf("unexpected file response %d %q", rec.Code, rec.Body.String())
+	}
+	rec := staticRequest(engine, http.MethodGet, "/robots.txt", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "User-agent: *" || rec.Header().Get("ETag") == "" {
+		t.Fatalf("unexpected StaticFile response %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/files/x", nil)
+	req.URL.Path = "/v1/files/../secret.txt"
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("path traversal must not escape the root, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticKeepsExistingRoutes(t *testing.T) {
+	engine := New()
+	engine.GET("/assets", func(c *Context) {
+		c.String(http.StatusOK, "custom")
+	})
+	engine.HEAD("/logo.txt", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+	engine.StaticFS("/assets", fstest.MapFS{"app.js": {Data: []byte("app")}})
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logo.txt"), []byte("logo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine.StaticFile("/logo.txt", filepath.Join(dir, "logo.txt"))
+
+	if rec := staticRequest(engine, http.MethodGet, "/assets", nil); rec.Body.String() != "custom" {
+		t.Fatalf("the bare prefix route must stay the user's, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/assets/app.js", nil); rec.Body.String() != "app" {
+		t.Fatalf("unexpected asset response %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := staticRequest(engine, http.MethodHead, "/logo.txt", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("the HEAD route must stay the user's, got %d", rec.Code)
+	}
+	if rec := staticRequest(engine, http.MethodGet, "/logo.txt", nil); rec.Body.String() != "logo" {
+		t.Fatalf("unexpected file response %d %q", rec.Code, rec.Body.String())
+	}
+}
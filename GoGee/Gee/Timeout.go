@@ -0,0 +1,258 @@
+package Gee
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var _ context.Context = (*Context)(nil)
+
+// TimeoutConfig configures TimeoutWithConfig.
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// StatusCode answers handlers that overrun; it defaults to 503. Use 504
+	// for handlers that mostly wait on upstream services.
+	StatusCode int
+	// OnTimeout writes the timeout response instead of the default JSON
+	// message.
+	OnTimeout HandlerFunc
+}
+
+func Timeout(timeout time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig runs the rest of the chain with a request context that
+// is cancelled after config.Timeout. The handlers write into a buffer, sent
+// once they return in time; when they overrun, the timeout response is sent
+// instead and whatever they write later is discarded. Streaming responses
+// are therefore held back until the handler returns.
+func TimeoutWithConfig(config TimeoutConfig) HandlerFunc {
+	if config.Timeout <= 0 {
+		panic("Gee: Timeout needs a positive duration")
+	}
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Rep.Context(), config.Timeout)
+		defer cancel()
+		c.Rep = c.Rep.WithContext(ctx)
+
+		tw := &timeoutWriter{header: c.Writer.Header().Clone(), status: http.StatusOK, size: noWritten}
+		// The handlers get their own Context: after a timeout they may still
+		// run while this one goes back to the pool.
+		tc := c.detach(tw)
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer func() {
+				err := recover()
+				if !tw.finish() {
+					// Nobody waits for a handler that overran, so its panic is
+					// only logged.
+					if err != nil {
+						log.Print(trace(fmt.Sprintf("panic after timeout: %v", err)))
+					}
+				} else {
+					panicked = err
+				}
+				close(done)
+			}()
+			tc.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if !tw.expire() {
+				// The handlers returned as the deadline passed.
+				<-done
+				break
+			}
+			c.Abort()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				c.Error(http.ErrHandlerTimeout)
+			} else {
+				c.Error(ctx.Err())
+			}
+			if config.OnTimeout != nil {
+				config.OnTimeout(c)
+				return
+			}
+			c.Fail(config.StatusCode, "request timed out")
+			return
+		}
+		if panicked != nil {
+			panic(panicked)
+		}
+		c.adopt(tc)
+		tw.copyTo(c.Writer)
+	}
+}
+
+// detach copies c for running the rest of the chain on another goroutine.
+func (c *Context) detach(w ResponseWriter) *Context {
+	return &Context{
+		Writer:       w,
+		Rep:          c.Rep,
+		Method:       c.Method,
+		Path:         c.Path,
+		Params:       append(Params(nil), c.Params...),
+		Keys:         maps.Clone(c.Keys),
+		engine:       c.engine,
+		handles:      c.handles,
+		index:        c.index,
+		StatusCode:   c.StatusCode,
+		fullPath:     c.fullPath,
+		errs:         append([]error(nil), c.errs...),
+		baseLogger:   c.baseLogger,
+		logger:       c.logger,
+		timing:       c.timing,
+		sessionStore: c.sessionStore,
+		csrf:         c.csrf,
+		csrfToken:    c.csrfToken,
+	}
+}
+
+// adopt takes back the state of a detached copy that finished in time.
+func (c *Context) adopt(tc *Context) {
+	if len(tc.Keys) > 0 {
+		if c.Keys == nil {
+			c.Keys = make(map[string]interface{}, len(tc.Keys))
+		}
+		maps.Copy(c.Keys, tc.Keys)
+	}
+	c.errs = append(c.errs[:0], tc.errs...)
+	c.index = tc.index
+	c.StatusCode = tc.StatusCode
+	c.Rep = tc.Rep
+	c.handlerStart, c.handlerEnd = tc.handlerStart, tc.handlerEnd
+	if tc.logger != nil {
+		c.logger = tc.logger
+	}
+	c.csrf, c.csrfToken = tc.csrf, tc.csrfToken
+	if tc.multipartForm != nil {
+		c.multipartForm = tc.multipartForm
+	}
+	if tc.session != nil {
+		tc.session.c = c
+		c.session = tc.session
+	}
+}
+
+// timeoutWriter buffers a response until it is copied to the real writer or
+// the deadline passes, after which writes fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	size     int
+	expired  bool
+	finished bool
+}
+
+var _ ResponseWriter = (*timeoutWriter)(nil)
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Interim responses cannot be sent while the response is buffered.
+	if w.expired || w.size != noWritten || informational(code) {
+		return
+	}
+	w.status = code
+	w.size = 0
+}
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size == noWritten {
+		w.size = 0
+	}
+}
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	n, err := w.buf.Write(data)
+	w.size += n
+	return n, err
+}
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+func (w *timeoutWriter) Written() bool {
+	return w.Size() != noWritten
+}
+
+// Flush is a no-op: nothing reaches the client before the handler returns.
+func (w *timeoutWriter) Flush() {}
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("Gee: connections cannot be hijacked under Timeout")
+}
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return nil
+}
+
+// expire makes later writes fail. It reports false when the handlers
+// already finished, in which case their response stands.
+func (w *timeoutWriter) expire() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished {
+		return false
+	}
+	w.expired = true
+	return true
+}
+
+// finish marks the handlers as returned and reports whether they did so
+// before expire.
+func (w *timeoutWriter) finish() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.finished = true
+	return !w.expired
+}
+
+func (w *timeoutWriter) copyTo(dst ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	header := dst.Header()
+	clear(header)
+	maps.Copy(header, w.header)
+	if w.size == noWritten {
+		return
+	}
+	dst.WriteHeader(w.status)
+	_, _ = dst.Write(w.buf.Bytes())
+}
@@ -0,0 +1,128 @@
+package Gee
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+func TestContextImplementsContext(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {
+		c.Set("tenant", "acme")
+		var ctx context.Context = c
+		if ctx.Value("tenant") != "acme" || ctx.Value(ctxKey{}) != "from request" || ctx.Value("missing") != nil {
+			t.Errorf("unexpected values %v %v", ctx.Value("tenant"), ctx.Value(ctxKey{}))
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the request deadline")
+		}
+		<-ctx.Done()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			t.Errorf("unexpected err %v", ctx.Err())
+		}
+	})
+	reqCtx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "from request"), 10*time.Millisecond)
+	defer cancel()
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx))
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	engine := New()
+	engine.Use(func(c *Context) {
+		c.SetHeader("X-Outer", "1")
+		c.Next()
+		if c.GetString("user") != "alice" {
+			t.Errorf("keys set under Timeout must be visible afterwards, got %v", c.Keys)
+		}
+	}, Timeout(time.Second))
+	engine.GET("/", func(c *Context) {
+		if _, ok := c.Deadline(); !ok {
+			t.Error("expected a deadline on the handler context")
+		}
+		c.Set("user", "alice")
+		c.SetHeader("X-Inner", "1")
+		c.String(http.StatusCreated, "done")
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusCreated || rec.Body.String() != "done" || rec.Header().Get("X-Outer") != "1" || rec.Header().Get("X-Inner") != "1" {
+		t.Fatalf("unexpected response %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestTimeoutDiscardsLateWrites(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	engine := New()
+	engine.Use(TimeoutWithConfig(TimeoutConfig{Timeout: 20 * time.Millisecond, StatusCode: http.StatusGatewayTimeout}))
+	engine.GET("/slow", func(c *Context) {
+		c.SetHeader("X-Partial", "1")
+		<-c.Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := c.Writer.Write([]byte("late"))
+		lateWrite <- err
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Fatalf("expected late write to fail, got %v", err)
+	}
+	if rec.Code != http.StatusGatewayTimeout || rec.Body.String() != "{\"message\":\"request timed out\"}\n" || rec.Header().Get("X-Partial") != "" {
+		t.Fatalf("unexpected response %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestTimeoutPropagatesPanics(t *testing.T) {
+	engine := New()
+	engine.Use(Recovery(), Timeout(time.Second))
+	engine.GET("/panic", func(c *Context) { panic("kaboom") })
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected Recovery to answer 500, got %d", rec.Code)
+	}
+}
+
+type logSink chan string
+
+func (s logSink) Write(p []byte) (int, error) {
+	s <- string(p)
+	return len(p), nil
+}
+
+func TestTimeoutLogsLatePanics(t *testing.T) {
+	logs := make(logSink, 1)
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	engine := New()
+	engine.Use(Timeout(20 * time.Millisecond))
+	engine.GET("/slow", func(c *Context) {
+		<-c.Done()
+		time.Sleep(10 * time.Millisecond)
+		panic("late kaboom")
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the timeout response, got %d", rec.Code)
+	}
+	select {
+	case line := <-logs:
+		if !strings.Contains(line, "panic after timeout: late kaboom") {
+			t.Fatalf("unexpected log %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the late panic was not logged")
+	}
+}
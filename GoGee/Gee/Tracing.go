@@ -0,0 +1,404 @@
+package Gee
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+func (id SpanID) IsValid() bool   { return id != SpanID{} }
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		hi, lo := rand.Uint64(), rand.Uint64()
+		for i := 0; i < 8; i++ {
+			id[i] = byte(hi >> (56 - 8*i))
+			id[8+i] = byte(lo >> (56 - 8*i))
+		}
+	}
+	return id
+}
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		v := rand.Uint64()
+		for i := 0; i < 8; i++ {
+			id[i] = byte(v >> (56 - 8*i))
+		}
+	}
+	return id
+}
+
+const traceFlagSampled = 0x01
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&traceFlagSampled != 0
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+var ErrInvalidTraceparent = errors.New("Gee: invalid traceparent header")
+
+// ParseTraceparent parses a W3C traceparent header. Versions above 00 are
+// read as 00, as the specification asks, ignoring any extra fields.
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	var sc SpanContext
+	flags := []byte{0}
+	if !isLowerHex(parts[1]) || !isLowerHex(parts[2]) || !isLowerHex(parts[3]) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	_, _ = hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	_, _ = hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	_, _ = hex.Decode(flags, []byte(parts[3]))
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	Name         string            `json:"name"`
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// SpanExporter receives sampled spans as they end.
+type SpanExporter interface {
+	ExportSpan(span SpanData)
+}
+
+// JSONSpanExporter writes one JSON object per span.
+type JSONSpanExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONSpanExporter(w io.Writer) *JSONSpanExporter {
+	return &JSONSpanExporter{w: w}
+}
+func (e *JSONSpanExporter) ExportSpan(span SpanData) {
+	data, err := json.Marshal(span)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.w.Write(append(data, '\n'))
+}
+
+// noopSpanExporter drops spans; they still propagate through contexts and
+// TraceTransport.
+type noopSpanExporter struct{}
+
+func (noopSpanExporter) ExportSpan(SpanData) {}
+
+// InMemorySpanExporter keeps spans for inspection in tests.
+type InMemorySpanExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *InMemorySpanExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+func (e *InMemorySpanExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+func (e *InMemorySpanExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Span is a unit of work in a trace. Its methods are safe for concurrent
+// use, and calls after End are ignored.
+type Span struct {
+	mu       sync.Mutex
+	sc       SpanContext
+	parent   SpanID
+	name     string
+	start    time.Time
+	attrs    map[string]string
+	errs     []string
+	ended    bool
+	exporter SpanExporter
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.sc
+}
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+func (s *Span) SetAttribute(key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = value
+}
+
+// RecordError marks the span as failed.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.errs = append(s.errs, err.Error())
+	}
+}
+
+// End finishes the span and exports it when it is sampled.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		Name:       s.name,
+		TraceID:    s.sc.TraceID.String(),
+		SpanID:     s.sc.SpanID.String(),
+		Start:      s.start,
+		End:        time.Now(),
+		Status:     "ok",
+		Attributes: s.attrs,
+	}
+	if s.parent.IsValid() {
+		data.ParentSpanID = s.parent.String()
+	}
+	if len(s.errs) > 0 {
+		data.Status = "error"
+		data.Error = strings.Join(s.errs, "; ")
+	}
+	s.mu.Unlock()
+	if s.exporter != nil && s.sc.Sampled() {
+		s.exporter.ExportSpan(data)
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span stored in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// StartSpan starts a child of the span in ctx, sharing its exporter. With
+// no span in ctx the child starts a new, unexported trace.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	var span *Span
+	if parent != nil {
+		span = newSpan(name, parent.sc, parent.exporter)
+	} else {
+		span = newSpan(name, SpanContext{}, nil)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+func newSpan(name string, parent SpanContext, exporter SpanExporter) *Span {
+	span := &Span{name: name, start: time.Now(), exporter: exporter}
+	if parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Flags: parent.Flags, TraceState: parent.TraceState}
+		span.parent = parent.SpanID
+	} else {
+		span.sc = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Flags: traceFlagSampled}
+	}
+	return span
+}
+
+// InjectTraceContext writes the traceparent and tracestate of the span in
+// ctx into header, so an outgoing request continues the trace.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	header.Set("traceparent", span.sc.Traceparent())
+	if span.sc.TraceState != "" {
+		header.Set("tracestate", span.sc.TraceState)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+// TraceTransport is an http.RoundTripper injecting the trace context of
+// each request's context before delegating to Base, or
+// http.DefaultTransport when Base is nil.
+type TraceTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if SpanFromContext(req.Context()) == nil {
+		return base.RoundTrip(req)
+	}
+	out := req.Clone(req.Context())
+	InjectTraceContext(req.Context(), out.Header)
+	return base.RoundTrip(out)
+}
+
+// Span returns the span started by Tracing, or nil.
+func (c *Context) Span() *Span {
+	return SpanFromContext(c.Rep.Context())
+}
+
+// TracingConfig configures TracingWithConfig.
+type TracingConfig struct {
+	// Exporter receives finished spans; they are dropped when it is nil.
+	// Use NewJSONSpanExporter(os.Stdout) to print them.
+	Exporter SpanExporter
+	// ResponseHeader announces the server span in a traceparent response
+	// header. It exposes trace IDs to clients, so it is off by default.
+	ResponseHeader bool
+}
+
+func Tracing() HandlerFunc {
+	return TracingWithConfig(TracingConfig{})
+}
+
+// TracingWithConfig starts a span per request, continuing the trace of an
+// incoming traceparent header. The span is named after the route pattern
+// and stored in the request context. Responses of 500 and above and
+// errors added with c.Error mark it as failed.
+func TracingWithConfig(config TracingConfig) HandlerFunc {
+	exporter := config.Exporter
+	if exporter == nil {
+		exporter = noopSpanExporter{}
+	}
+	return func(c *Context) {
+		var parent SpanContext
+		if sc, err := ParseTraceparent(c.Rep.Header.Get("traceparent")); err == nil {
+			parent = sc
+			parent.TraceState = normalizeTraceState(c.Rep.Header.Values("tracestate"))
+		}
+		name := c.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+		span := newSpan(name, parent, exporter)
+		span.SetAttribute("http.method", c.Method)
+		span.SetAttribute("http.target", c.Rep.URL.RequestURI())
+		if route := c.FullPath(); route != "" {
+			span.SetAttribute("http.route", route)
+		}
+		c.Rep = c.Rep.WithContext(ContextWithSpan(c.Rep.Context(), span))
+		if config.ResponseHeader {
+			c.SetHeader("traceparent", span.sc.Traceparent())
+		}
+
+		defer func() {
+			if err := recover(); err != nil {
+				span.RecordError(fmt.Errorf("panic: %v", err))
+				span.End()
+				panic(err)
+			}
+			status := c.Writer.Status()
+			span.SetAttribute("http.status_code", strconv.Itoa(status))
+			for _, err := range c.errs {
+				span.RecordError(err)
+			}
+			if status >= http.StatusInternalServerError && len(c.errs) == 0 {
+				span.RecordError(errors.New(http.StatusText(status)))
+			}
+			span.End()
+		}()
+		c.Next()
+	}
+}
+
+// normalizeTraceState joins the tracestate headers, dropping the whole
+// value when it exceeds the 32 list members the specification allows.
+func normalizeTraceState(values []string) string {
+	var members []string
+	for _, value := range values {
+		for _, member := range strings.Split(value, ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				members = append(members, member)
+			}
+		}
+	}
+	if len(members) > 32 {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
@@ -0,0 +1,140 @@
+package Gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled() {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if got := sc.Traceparent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected round trip %q", got)
+	}
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future"); err != nil {
+		t.Fatalf("future versions must be accepted: %v", err)
+	}
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	exporter := &InMemorySpanExporter{}
+	var outgoing http.Header
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Clone()
+	}))
+	defer downstream.Close()
+	client := &http.Client{Transport: &TraceTransport{}}
+
+	engine := New()
+	engine.Use(TracingWithConfig(TracingConfig{Exporter: exporter, ResponseHeader: true}))
+	engine.GET("/users/:id", func(c *Context) {
+		_, child := StartSpan(c.Rep.Context(), "load user")
+		child.End()
+		req, _ := http.NewRequestWithContext(c.Rep.Context(), http.MethodGet, downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("downstream call failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+		c.String(http.StatusOK, "%s", c.Span().SpanContext().TraceID)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("handler saw trace %q", rec.Body.String())
+	}
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected child and server spans, got %+v", spans)
+	}
+	child, server := spans[0], spans[1]
+	if server.Name != "GET /users/:id" || server.ParentSpanID != "00f067aa0ba902b7" || server.Status != "ok" ||
+		server.Attributes["http.status_code"] != "200" || server.Attributes["http.route"] != "/users/:id" {
+		t.Fatalf("unexpected server span %+v", server)
+	}
+	if child.ParentSpanID != server.SpanID || child.TraceID != server.TraceID {
+		t.Fatalf("child span not linked to server span: %+v", child)
+	}
+	if got := rec.Header().Get("traceparent"); got != "00-"+server.TraceID+"-"+server.SpanID+"-01" {
+		t.Fatalf("unexpected response traceparent %q", got)
+	}
+	if outgoing.Get("traceparent") != "00-"+server.TraceID+"-"+server.SpanID+"-01" || outgoing.Get("tracestate") != "congo=t61rcWkgMzE" {
+		t.Fatalf("outgoing call did not carry the span context: %v", outgoing)
+	}
+}
+
+func TestTracingDefaults(t *testing.T) {
+	engine := New()
+	engine.Use(Tracing())
+	engine.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "%t", c.Span().SpanContext().IsValid())
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Body.String() != "true" {
+		t.Fatalf("expected a span in the request context, got %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("traceparent"); got != "" {
+		t.Fatalf("traceparent response header must be opt-in, got %q", got)
+	}
+}
+
+func TestTracingRecordsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(TracingWithConfig(TracingConfig{Exporter: NewJSONSpanExporter(&buf)}), Recovery())
+	engine.GET("/fail", func(c *Context) {
+		c.Error(errors.New("db unavailable"))
+		c.String(http.StatusServiceUnavailable, "down")
+	})
+	engine.GET("/panic", func(c *Context) { panic("kaboom") })
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 exported spans, unsampled ones skipped, got %q", buf.String())
+	}
+	var failed, panicked SpanData
+	_ = json.Unmarshal([]byte(lines[0]), &failed)
+	_ = json.Unmarshal([]byte(lines[1]), &panicked)
+	if failed.Status != "error" || failed.Error != "db unavailable" || failed.ParentSpanID != "" {
+		t.Fatalf("unexpected failed span %+v", failed)
+	}
+	if panicked.Status != "error" || panicked.Error != "panic: kaboom" || panicked.Attributes["http.status_code"] != "500" {
+		t.Fatalf("unexpected panicked span %+v", panicked)
+	}
+}
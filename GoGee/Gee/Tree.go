@@ -1,58 +1,237 @@
 package Gee
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
+type nodeKind uint8
+
+const (
+	staticNode nodeKind = iota
+	paramNode
+	catchAllNode
+)
+
+// node is an edge of the compressed radix tree. Static edges hold a shared
+// byte prefix, param edges consume one path segment and catch-all edges
+// consume the rest of the path. Wildcards only start right after a '/'.
+// A node has one param edge per constraint, the unconstrained one last.
 type node struct {
-	pattern  string
-	part     string
-	children []*node
-	isWild   bool
+	path       string
+	kind       nodeKind
+	indices    string
+	children   []*node
+	params     []*node
+	catchAll   *node
+	constraint string
+	match      func(string) bool
+	pattern    string
+	paramNames []string
+	handlers   []HandlerFunc
+	chain      []HandlerFunc
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
 }
 
-func (fa *node) MatchChild(part string) *node {
-	for _, child := range fa.children {
-		if child.part == part || child.isWild {
+// insertStatic walks or creates static edges for s below fa, splitting
+// existing edges when they only share part of s, and returns the node at
+// which s ends.
+func (fa *node) insertStatic(s string) *node {
+	for s != "" {
+		i := strings.IndexByte(fa.indices, s[0])
+		if i < 0 {
+			child := &node{path: s, kind: staticNode}
+			fa.indices += string(s[0])
+			fa.children = append(fa.children, child)
 			return child
 		}
+		child := fa.children[i]
+		l := longestCommonPrefix(child.path, s)
+		if l < len(child.path) {
+			// The existing node keeps its identity as the suffix so that
+			// leaves handed out earlier stay valid.
+			prefix := &node{
+				path:     child.path[:l],
+				kind:     staticNode,
+				indices:  string(child.path[l]),
+				children: []*node{child},
+			}
+			child.path = child.path[l:]
+			fa.children[i] = prefix
+			child = prefix
+		}
+		fa, s = child, s[l:]
 	}
-	return nil
+	return fa
 }
-func (fa *node) MatchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range fa.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+
+// paramEdge returns the param edge of fa for constraint, creating it when
+// needed.
+func (fa *node) paramEdge(pattern string, part string, constraint string, constraints map[string]func(string) bool) *node {
+	for _, child := range fa.params {
+		if child.constraint == constraint {
+			return child
 		}
 	}
-	return nodes
+	child := &node{path: part, kind: paramNode, constraint: constraint}
+	if constraint == "" {
+		fa.params = append(fa.params, child)
+		return child
+	}
+	match, err := resolveConstraint(constraint, constraints)
+	if err != nil {
+		panic(fmt.Sprintf("Gee: invalid constraint %q in route %q: %v", constraint, pattern, err))
+	}
+	child.match = match
+	i := len(fa.params)
+	if i > 0 && fa.params[i-1].constraint == "" {
+		i--
+	}
+	fa.params = append(fa.params[:i], append([]*node{child}, fa.params[i:]...)...)
+	return child
 }
-func (fa *node) Insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		fa.pattern = pattern
-		return
+
+// Insert adds the nodes needed by pattern and returns its leaf together with
+// the wildcard names in the order they appear. Patterns that only differ by
+// wildcard names share a leaf, which is how the router detects ambiguity.
+// Constraints name an entry of constraints or a built-in, or are compiled as
+// regular expressions.
+func (fa *node) Insert(pattern string, constraints map[string]func(string) bool) (*node, []string) {
+	parts := parsePattern(pattern)
+	if len(parts) == 0 {
+		return fa.insertStatic("/"), nil
 	}
-	part := parts[height]
-	child := fa.MatchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		fa.children = append(fa.children, child)
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	cur, static := fa, ""
+	for _, part := range parts {
+		if part[0] != ':' && part[0] != '*' {
+			static += "/" + part
+			continue
+		}
+		name, constraint := splitParam(part[1:])
+		if part[0] == ':' && name == "" {
+			panic(fmt.Sprintf("Gee: wildcard in route %q must be named", pattern))
+		}
+		if part[0] == '*' && constraint != "" {
+			panic(fmt.Sprintf("Gee: catch-all in route %q cannot have a constraint", pattern))
+		}
+		if name != "" {
+			if seen[name] {
+				panic(fmt.Sprintf("Gee: wildcard %q is used more than once in route %q", name, pattern))
+			}
+			seen[name] = true
+		}
+		cur = cur.insertStatic(static + "/")
+		static = ""
+		if part[0] == ':' {
+			cur = cur.paramEdge(pattern, part, constraint, constraints)
+		} else {
+			if cur.catchAll == nil {
+				cur.catchAll = &node{path: part, kind: catchAllNode}
+			}
+			cur = cur.catchAll
+		}
+		names = append(names, name)
 	}
-	child.Insert(pattern, parts, height+1)
+	return cur.insertStatic(static), names
 }
-func (fa *node) Search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(fa.part, "*") {
-		if fa.pattern == "" {
-			return nil
+
+// Search matches path against the subtree rooted at fa, appending wildcard
+// values to params; keys are filled in by the leaf afterwards. Static edges
+// win over params, params over catch-alls, and a failed branch is backtracked
+// before the next kind is tried. Constrained params are tried before the
+// unconstrained one and only take segments their constraint accepts.
+func (fa *node) Search(path string, params Params) (*node, Params) {
+	mark := len(params)
+	switch fa.kind {
+	case staticNode:
+		if !strings.HasPrefix(path, fa.path) {
+			return nil, params
+		}
+		path = path[len(fa.path):]
+	case paramNode:
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 || fa.match != nil && !fa.match(path[:end]) {
+			return nil, params
+		}
+		params = append(params, Param{Value: path[:end]})
+		path = path[end:]
+	case catchAllNode:
+		if path == "" || fa.pattern == "" {
+			return nil, params
+		}
+		return fa, append(params, Param{Value: path})
+	}
+	if path == "" {
+		if fa.pattern != "" {
+			return fa, params
+		}
+		return nil, params[:mark]
+	}
+	if i := strings.IndexByte(fa.indices, path[0]); i >= 0 {
+		if result, ps := fa.children[i].Search(path, params); result != nil {
+			return result, ps
 		}
-		return fa
 	}
-	part := parts[height]
-	children := fa.MatchChildren(part)
-	for _, child := range children {
-		result := child.Search(parts, height+1)
-		if result != nil {
-			return result
+	for _, child := range fa.params {
+		if result, ps := child.Search(path, params); result != nil {
+			return result, ps
 		}
 	}
-	return nil
+	if fa.catchAll != nil {
+		if result, ps := fa.catchAll.Search(path, params); result != nil {
+			return result, ps
+		}
+	}
+	return nil, params[:mark]
+}
+
+// nameParams assigns the leaf's wildcard names to the values collected by
+// Search, dropping values of unnamed catch-alls.
+func (fa *node) nameParams(params Params) Params {
+	j := 0
+	for i, name := range fa.paramNames {
+		if name == "" {
+			continue
+		}
+		params[j] = Param{Key: name, Value: params[i].Value}
+		j++
+	}
+	return params[:j]
+}
+
+// cleanPath normalises a request path the same way patterns are parsed:
+// empty segments and trailing slashes are dropped.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] == '/' && !strings.Contains(p, "//") {
+		if len(p) > 1 && p[len(p)-1] == '/' {
+			p = p[:len(p)-1]
+		}
+		return p
+	}
+	var b strings.Builder
+	for _, seg := range strings.Split(p, "/") {
+		if seg != "" {
+			b.WriteByte('/')
+			b.WriteString(seg)
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
 }
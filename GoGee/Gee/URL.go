@@ -0,0 +1,124 @@
+package Gee
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var ErrRouteNotFound = errors.New("Gee: no route with that name")
+
+// RouteRef is returned when a route is registered so it can be decorated
+// afterwards, e.g. engine.GET("/users/:id", show).Name("user.show").
+type RouteRef struct {
+	engine  *Engine
+	router  *Router
+	indexes []int
+}
+
+// Name registers name for the route. Names are unique per engine; reusing one
+// for a different pattern panics.
+func (ref *RouteRef) Name(name string) *RouteRef {
+	routes := ref.router.routes
+	for _, index := range ref.indexes {
+		pattern := routes[index].Pattern
+		if existing, ok := ref.engine.routeNames[name]; ok && existing.pattern != pattern {
+			panic(fmt.Sprintf("Gee: route name %q is already used by %s", name, existing.pattern))
+		}
+		named := &namedRoute{pattern: pattern, constraints: routes[index].Constraints}
+		for key, constraint := range named.constraints {
+			// Registration already compiled the constraint, so this cannot fail.
+			match, _ := resolveConstraint(constraint, ref.router.constraints)
+			if named.match == nil {
+				named.match = make(map[string]func(string) bool)
+			}
+			named.match[key] = match
+		}
+		ref.engine.routeNames[name] = named
+		routes[index].Name = name
+	}
+	return ref
+}
+
+// namedRoute is a route registered with Name, with the matchers of its
+// constrained params.
+type namedRoute struct {
+	pattern     string
+	constraints map[string]string
+	match       map[string]func(string) bool
+}
+
+// URL builds the path of the route registered as name. pairs alternates
+// param names and values; values are formatted with fmt.Sprint and escaped.
+// An unnamed catch-all takes the value of the "*" pair. Values must satisfy
+// the constraints of their params. Pairs that do not name a wildcard of the
+// route are added as query string.
+func (engine *Engine) URL(name string, pairs ...interface{}) (string, error) {
+	named, ok := engine.routeNames[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrRouteNotFound, name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("Gee: url %q needs key/value pairs, got %d arguments", name, len(pairs))
+	}
+	values := make(map[string]string, len(pairs)/2)
+	keys := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", fmt.Errorf("Gee: url %q param name %v is not a string", name, pairs[i])
+		}
+		if _, dup := values[key]; !dup {
+			keys = append(keys, key)
+		}
+		values[key] = fmt.Sprint(pairs[i+1])
+	}
+
+	used := make(map[string]bool)
+	var b strings.Builder
+	for _, part := range parsePattern(named.pattern) {
+		b.WriteByte('/')
+		switch part[0] {
+		case ':', '*':
+			key, _ := splitParam(part[1:])
+			if key == "" {
+				key = "*"
+			}
+			value := values[key]
+			if value == "" {
+				return "", fmt.Errorf("Gee: url %q is missing param %q", name, key)
+			}
+			if match := named.match[key]; match != nil && !match(value) {
+				return "", fmt.Errorf("Gee: url %q param %q does not match <%s>", name, key, named.constraints[key])
+			}
+			used[key] = true
+			if part[0] == ':' {
+				b.WriteString(url.PathEscape(value))
+				continue
+			}
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		default:
+			b.WriteString(part)
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteByte('/')
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		if !used[key] {
+			query.Set(key, values[key])
+		}
+	}
+	if len(query) > 0 {
+		b.WriteByte('?')
+		b.WriteString(query.Encode())
+	}
+	return b.String(), nil
+}
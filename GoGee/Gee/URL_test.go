@@ -0,0 +1,94 @@
+package Gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEngineURL(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {}).Name("home")
+	engine.Group("/users").GET("/:id", func(c *Context) {}).Name("user.show")
+	engine.GET("/files/*filepath", func(c *Context) {}).Name("files")
+	engine.GET("/assets/*", func(c *Context) {}).Name("assets")
+	engine.GET("/posts/:id<int>/:slug<[a-z-]+>", func(c *Context) {}).Name("post")
+
+	cases := []struct {
+		name  string
+		pairs []interface{}
+		want  string
+	}{
+		{"home", nil, "/"},
+		{"user.show", []interface{}{"id", 42}, "/users/42"},
+		{"user.show", []interface{}{"id", "a b/c"}, "/users/a%20b%2Fc"},
+		{"user.show", []interface{}{"id", 1, "tab", "posts"}, "/users/1?tab=posts"},
+		{"files", []interface{}{"filepath", "css/my app.css"}, "/files/css/my%20app.css"},
+		{"assets", []interface{}{"*", "js/app.js"}, "/assets/js/app.js"},
+		{"post", []interface{}{"id", 7, "slug", "hello-gee"}, "/posts/7/hello-gee"},
+	}
+	for _, tc := range cases {
+		got, err := engine.URL(tc.name, tc.pairs...)
+		if err != nil || got != tc.want {
+			t.Fatalf("URL(%s, %v) = %q, %v; want %q", tc.name, tc.pairs, got, err, tc.want)
+		}
+	}
+
+	if _, err := engine.URL("user.show"); err == nil || !strings.Contains(err.Error(), `"id"`) {
+		t.Fatalf("expected missing param error, got %v", err)
+	}
+	if _, err := engine.URL("post", "id", "x", "slug", "hello"); err == nil || !strings.Contains(err.Error(), "<int>") {
+		t.Fatalf("expected int constraint error, got %v", err)
+	}
+	if _, err := engine.URL("post", "id", 7, "slug", "Hello"); err == nil || !strings.Contains(err.Error(), "<[a-z-]+>") {
+		t.Fatalf("expected regexp constraint error, got %v", err)
+	}
+	if _, err := engine.URL("nope"); !errors.Is(err, ErrRouteNotFound) {
+		t.Fatalf("expected ErrRouteNotFound, got %v", err)
+	}
+	if _, err := engine.URL("user.show", "id"); err == nil {
+		t.Fatal("expected error for odd pairs")
+	}
+}
+
+func TestRouteNameListedAndUnique(t *testing.T) {
+	engine := New()
+	engine.Any("/ping", func(c *Context) {}).Name("ping")
+	for _, route := range engine.Routes() {
+		if route.Name != "ping" {
+			t.Fatalf("expected every Any route to be named, got %+v", route)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic when reusing a route name")
+		}
+	}()
+	engine.GET("/pong", func(c *Context) {}).Name("ping")
+}
+
+func TestTemplateURLHelper(t *testing.T) {
+	engine := New()
+	dir := t.TempDir()
+	tpl := `<a href="{{url "user.show" "id" .ID}}">profile</a>`
+	if err := os.WriteFile(filepath.Join(dir, "link.tmpl"), []byte(tpl), 0o644); err != nil {
+		t.Fatalf("write temp template failed: %v", err)
+	}
+	engine.GET("/users/:id", func(c *Context) {}).Name("user.show")
+	engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	engine.GET("/link", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "link.tmpl", H{"ID": 7})
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/link", nil)
+	engine.ServeHTTP(rec, req)
+	if rec.Body.String() != `<a href="/users/7">profile</a>` {
+		t.Fatalf("unexpected template body: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,294 @@
+package Gee
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// StructValidator checks a bound struct. Replace Validator to plug in a
+// different implementation.
+type StructValidator interface {
+	ValidateStruct(obj interface{}) error
+}
+
+var Validator StructValidator = defaultValidator{}
+
+// FieldError describes one failed `binding` rule. Field is the name the
+// client used (json, form, query, uri or header tag), dotted for nested
+// structs.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, fe := range ve {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrBindingTag reports a malformed `binding` tag. It is returned by the
+// first validation of the struct type, so it shows up in the first test
+// that binds it.
+var ErrBindingTag = errors.New("Gee: invalid binding tag")
+
+// defaultValidator understands required, omitempty, min, max, len, email
+// and oneof. Rules apply to zero values too; omitempty skips the other
+// rules of a zero field and nil pointers are only checked by required.
+// Tags are parsed once per struct type.
+type defaultValidator struct{}
+
+func (defaultValidator) ValidateStruct(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	rules, err := structRulesFor(v.Type())
+	if err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	rules.validate(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func fieldName(sf reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "uri", "header", "xml"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+type structRules struct {
+	fields []fieldRules
+}
+
+type fieldRules struct {
+	index     int
+	name      string
+	anonymous bool
+	required  bool
+	omitEmpty bool
+	rules     []rule
+	// nested holds the rules of a struct field, through any pointers.
+	nested *structRules
+}
+
+type rule struct {
+	name    string
+	param   string
+	limit   float64
+	options []string
+}
+
+var (
+	structRulesCache sync.Map
+	structRulesMu    sync.Mutex
+)
+
+func structRulesFor(t reflect.Type) (*structRules, error) {
+	if cached, ok := structRulesCache.Load(t); ok {
+		return cached.(*structRules), nil
+	}
+	structRulesMu.Lock()
+	defer structRulesMu.Unlock()
+	building := make(map[reflect.Type]*structRules)
+	rules, err := compileStructRules(t, building)
+	if err != nil {
+		return nil, err
+	}
+	for bt, br := range building {
+		structRulesCache.Store(bt, br)
+	}
+	return rules, nil
+}
+
+func compileStructRules(t reflect.Type, building map[reflect.Type]*structRules) (*structRules, error) {
+	if cached, ok := structRulesCache.Load(t); ok {
+		return cached.(*structRules), nil
+	}
+	if rules, ok := building[t]; ok {
+		// Recursive types share the rules being built.
+		return rules, nil
+	}
+	rules := &structRules{}
+	building[t] = rules
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		fr := fieldRules{index: i, name: fieldName(sf), anonymous: sf.Anonymous}
+		if tag := sf.Tag.Get("binding"); tag != "" && tag != "-" {
+			if err := fr.parse(t.Name()+"."+sf.Name, tag); err != nil {
+				return nil, err
+			}
+		}
+		inner := sf.Type
+		for inner.Kind() == reflect.Ptr {
+			inner = inner.Elem()
+		}
+		if isNestedStruct(inner) {
+			nested, err := compileStructRules(inner, building)
+			if err != nil {
+				return nil, err
+			}
+			fr.nested = nested
+		}
+		if fr.required || len(fr.rules) > 0 || fr.nested != nil {
+			rules.fields = append(rules.fields, fr)
+		}
+	}
+	return rules, nil
+}
+
+func (fr *fieldRules) parse(field string, tag string) error {
+	for _, item := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		r := rule{name: name, param: param}
+		switch name {
+		case "":
+			continue
+		case "required":
+			fr.required = true
+			continue
+		case "omitempty":
+			fr.omitEmpty = true
+			continue
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return fmt.Errorf("%w: rule %s on %s needs a numeric parameter, got %q", ErrBindingTag, name, field, param)
+			}
+			r.limit = limit
+		case "email":
+		case "oneof":
+			r.options = strings.Fields(param)
+			if len(r.options) == 0 {
+				return fmt.Errorf("%w: rule oneof on %s needs options", ErrBindingTag, field)
+			}
+		default:
+			return fmt.Errorf("%w: unknown rule %q on %s", ErrBindingTag, name, field)
+		}
+		fr.rules = append(fr.rules, r)
+	}
+	return nil
+}
+
+func (rules *structRules) validate(v reflect.Value, prefix string, errs *ValidationErrors) {
+	for i := range rules.fields {
+		fr := &rules.fields[i]
+		fv := v.Field(fr.index)
+		name := prefix + fr.name
+		fr.validate(fv, name, errs)
+		if fr.nested == nil {
+			continue
+		}
+		inner := fv
+		for inner.Kind() == reflect.Ptr && !inner.IsNil() {
+			inner = inner.Elem()
+		}
+		if inner.Kind() != reflect.Struct {
+			continue
+		}
+		if fr.anonymous {
+			fr.nested.validate(inner, prefix, errs)
+		} else {
+			fr.nested.validate(inner, name+".", errs)
+		}
+	}
+}
+
+func (fr *fieldRules) validate(fv reflect.Value, name string, errs *ValidationErrors) {
+	if fv.IsZero() {
+		if fr.required {
+			*errs = append(*errs, FieldError{Field: name, Rule: "required", Message: name + " is required"})
+			return
+		}
+		if fr.omitEmpty {
+			return
+		}
+	}
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+	for _, r := range fr.rules {
+		if message, ok := r.check(fv, name); !ok {
+			*errs = append(*errs, FieldError{Field: name, Rule: r.name, Param: r.param, Message: message})
+		}
+	}
+}
+
+// check returns a message and false when fv breaks r.
+func (r rule) check(fv reflect.Value, name string) (string, bool) {
+	switch r.name {
+	case "min", "max", "len":
+		size, unit := measure(fv)
+		switch {
+		case r.name == "min" && size < r.limit:
+			return fmt.Sprintf("%s must be at least %s%s", name, r.param, unit), false
+		case r.name == "max" && size > r.limit:
+			return fmt.Sprintf("%s must be at most %s%s", name, r.param, unit), false
+		case r.name == "len" && size != r.limit:
+			return fmt.Sprintf("%s must be exactly %s%s", name, r.param, unit), false
+		}
+	case "email":
+		s := fmt.Sprint(fv.Interface())
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return name + " must be a valid email address", false
+		}
+	case "oneof":
+		s := fmt.Sprint(fv.Interface())
+		for _, option := range r.options {
+			if s == option {
+				return "", true
+			}
+		}
+		return fmt.Sprintf("%s must be one of [%s]", name, r.param), false
+	}
+	return "", true
+}
+
+// measure returns the value compared by min/max/len: the rune count of a
+// string, the length of a collection or the number itself.
+func measure(fv reflect.Value) (float64, string) {
+	switch fv.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(fv.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(fv.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), ""
+	}
+	return 0, ""
+}
@@ -0,0 +1,499 @@
+package Gee
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types, equal to the RFC 6455 opcodes.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// WebSocket close codes from RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	websocketGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxControlPayload     = 125
+	controlWriteTimeout   = 5 * time.Second
+	websocketVersion      = "13"
+	websocketUpgradeToken = "websocket"
+)
+
+var (
+	ErrBadHandshake = errors.New("Gee: websocket handshake failed")
+	ErrReadLimit    = errors.New("Gee: websocket message exceeds read limit")
+	ErrCloseSent    = errors.New("Gee: websocket close already sent")
+)
+
+// CloseError is returned by ReadMessage once the peer sends a close frame.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("Gee: websocket closed with code %d %s", e.Code, e.Text)
+}
+
+// Upgrader performs the server side of the RFC 6455 opening handshake.
+type Upgrader struct {
+	// CheckOrigin decides whether a cross-origin request may upgrade. When
+	// nil, requests with an Origin whose host differs from Host are refused.
+	CheckOrigin func(req *http.Request) bool
+	// Subprotocols lists supported protocols in order of preference.
+	Subprotocols []string
+	// ReadLimit caps the size of a single message. Zero means
+	// DefaultWSReadLimit; a negative value removes the cap.
+	ReadLimit int64
+}
+
+// DefaultWSReadLimit is the message size cap of upgraders without one.
+const DefaultWSReadLimit = 16 << 20
+
+var DefaultUpgrader = &Upgrader{}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (u *Upgrader) selectSubprotocol(req *http.Request) string {
+	offered := make(map[string]bool)
+	for _, value := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, part := range strings.Split(value, ",") {
+			offered[strings.TrimSpace(part)] = true
+		}
+	}
+	for _, protocol := range u.Subprotocols {
+		if offered[protocol] {
+			return protocol
+		}
+	}
+	return ""
+}
+
+// Upgrade validates the handshake, hijacks the connection and answers with
+// 101 Switching Protocols. On failure it aborts the chain with an error
+// response and returns ErrBadHandshake.
+func (u *Upgrader) Upgrade(c *Context) (*Conn, error) {
+	req := c.Rep
+	fail := func(code int, reason string) (*Conn, error) {
+		if code == http.StatusUpgradeRequired {
+			c.SetHeader("Sec-WebSocket-Version", websocketVersion)
+		}
+		c.Fail(code, reason)
+		return nil, fmt.Errorf("%w: %s", ErrBadHandshake, reason)
+	}
+	if req.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "websocket upgrade requires GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") || !headerContainsToken(req.Header, "Upgrade", websocketUpgradeToken) {
+		return fail(http.StatusBadRequest, "missing websocket upgrade headers")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != websocketVersion {
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		return fail(http.StatusForbidden, "websocket origin not allowed")
+	}
+	if c.Writer.Written() {
+		return nil, fmt.Errorf("%w: response already written", ErrBadHandshake)
+	}
+
+	netConn, rw, err := c.Writer.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, "websocket hijack not supported")
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	subprotocol := u.selectSubprotocol(req)
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if _, err := rw.WriteString(b.String()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return &Conn{
+		conn:        netConn,
+		br:          rw.Reader,
+		bw:          rw.Writer,
+		subprotocol: subprotocol,
+		readLimit:   u.readLimit(),
+	}, nil
+}
+
+func (u *Upgrader) readLimit() int64 {
+	switch {
+	case u.ReadLimit == 0:
+		return DefaultWSReadLimit
+	case u.ReadLimit < 0:
+		return 0
+	}
+	return u.ReadLimit
+}
+
+// Upgrade switches the request to the WebSocket protocol using
+// DefaultUpgrader.
+func (c *Context) Upgrade() (*Conn, error) {
+	return DefaultUpgrader.Upgrade(c)
+}
+
+// WSHandler serves an upgraded connection. The connection is closed when it
+// returns.
+type WSHandler func(c *Context, conn *Conn)
+
+// WS registers a GET route that upgrades to WebSocket before calling handler.
+func (routerGroup *RouterGroup) WS(pattern string, handler WSHandler) *RouteRef {
+	return routerGroup.GET(pattern, func(c *Context) {
+		conn, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(c, conn)
+	})
+}
+func (engine *Engine) WS(pattern string, handler WSHandler) *RouteRef {
+	return engine.routerGroup.WS(pattern, handler)
+}
+
+// Conn is a server-side WebSocket connection. One goroutine may read while
+// any number of goroutines write.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	bw          *bufio.Writer
+	subprotocol string
+	readLimit   int64
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	pingHandler func(data string) error
+	pongHandler func(data string) error
+}
+
+func (conn *Conn) Subprotocol() string {
+	return conn.subprotocol
+}
+func (conn *Conn) RemoteAddr() net.Addr {
+	return conn.conn.RemoteAddr()
+}
+
+// SetReadLimit caps the size of a single message; zero or less removes the
+// cap.
+func (conn *Conn) SetReadLimit(limit int64) {
+	conn.readLimit = limit
+}
+func (conn *Conn) SetReadDeadline(t time.Time) error {
+	return conn.conn.SetReadDeadline(t)
+}
+func (conn *Conn) SetWriteDeadline(t time.Time) error {
+	return conn.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler replaces the default ping handler, which answers with a
+// pong carrying the same payload.
+func (conn *Conn) SetPingHandler(handler func(data string) error) {
+	conn.pingHandler = handler
+}
+func (conn *Conn) SetPongHandler(handler func(data string) error) {
+	conn.pongHandler = handler
+}
+
+func isControl(opcode int) bool {
+	return opcode >= CloseMessage
+}
+
+func (conn *Conn) writeFrame(opcode int, payload []byte) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	if conn.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		conn.closeSent = true
+	}
+	var header [10]byte
+	header[0] = 0x80 | byte(opcode)
+	n := 2
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n = 4
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n = 10
+	}
+	if _, err := conn.bw.Write(header[:n]); err != nil {
+		return err
+	}
+	if _, err := conn.bw.Write(payload); err != nil {
+		return err
+	}
+	return conn.bw.Flush()
+}
+
+// WriteMessage sends a complete message. It is safe for concurrent use.
+func (conn *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return errors.New("Gee: websocket control payload exceeds 125 bytes")
+		}
+	case CloseMessage:
+		return errors.New("Gee: use WriteClose to send a close frame")
+	default:
+		return fmt.Errorf("Gee: unknown websocket message type %d", messageType)
+	}
+	return conn.writeFrame(messageType, data)
+}
+func (conn *Conn) WriteText(text string) error {
+	return conn.WriteMessage(TextMessage, []byte(text))
+}
+
+// WriteClose sends a close frame with code and reason. The connection stays
+// open so the peer's close frame can still be read.
+func (conn *Conn) WriteClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	_ = conn.conn.SetWriteDeadline(time.Now().Add(controlWriteTimeout))
+	return conn.writeFrame(CloseMessage, payload)
+}
+
+// Close sends a normal close frame if none was sent yet and closes the
+// underlying connection.
+func (conn *Conn) Close() error {
+	_ = conn.WriteClose(CloseNormalClosure, "")
+	return conn.conn.Close()
+}
+
+// fail closes the connection with code after a protocol violation.
+func (conn *Conn) fail(code int, err error) error {
+	_ = conn.WriteClose(code, "")
+	_ = conn.conn.Close()
+	return err
+}
+
+const websocketReadChunk = 64 << 10
+
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+func (conn *Conn) readFrame(buffered int64) (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(conn.br, head[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: head[0]&0x80 != 0, opcode: int(head[0] & 0x0f)}
+	if head[0]&0x70 != 0 {
+		return frame{}, conn.fail(CloseProtocolError, errors.New("Gee: websocket reserved bits set"))
+	}
+	if head[1]&0x80 == 0 {
+		return frame{}, conn.fail(CloseProtocolError, errors.New("Gee: websocket client frame is not masked"))
+	}
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(conn.br, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(conn.br, ext[:]); err != nil {
+			return frame{}, err
+		}
+		if ext[0]&0x80 != 0 {
+			return frame{}, conn.fail(CloseProtocolError, errors.New("Gee: websocket frame length overflows"))
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if isControl(f.opcode) && (length > maxControlPayload || !f.fin) {
+		return frame{}, conn.fail(CloseProtocolError, errors.New("Gee: websocket control frame is fragmented or too long"))
+	}
+	if !isControl(f.opcode) && conn.readLimit > 0 && buffered+length > conn.readLimit {
+		return frame{}, conn.fail(CloseMessageTooBig, ErrReadLimit)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(conn.br, mask[:]); err != nil {
+		return frame{}, err
+	}
+	// The buffer grows with the bytes that actually arrive rather than the
+	// length the peer declares.
+	var payload bytes.Buffer
+	payload.Grow(int(min(length, websocketReadChunk)))
+	if _, err := io.CopyN(&payload, conn.br, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return frame{}, err
+	}
+	f.payload = payload.Bytes()
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+func (conn *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return conn.fail(CloseProtocolError, errors.New("Gee: websocket close payload too short"))
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !utf8.ValidString(closeErr.Text) {
+			return conn.fail(CloseInvalidFramePayloadData, errors.New("Gee: websocket close reason is not UTF-8"))
+		}
+	}
+	code := closeErr.Code
+	if code == CloseNoStatusReceived {
+		code = CloseNormalClosure
+	}
+	_ = conn.WriteClose(code, "")
+	_ = conn.conn.Close()
+	return closeErr
+}
+
+// ReadMessage returns the next complete data message, reassembling
+// fragments and answering control frames on the way. After the peer closes
+// the connection it returns a *CloseError.
+func (conn *Conn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		f, err := conn.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case PingMessage:
+			if conn.pingHandler != nil {
+				err = conn.pingHandler(string(f.payload))
+			} else {
+				if err = conn.writeFrame(PongMessage, f.payload); err == ErrCloseSent {
+					err = nil
+				}
+			}
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if conn.pongHandler != nil {
+				if err := conn.pongHandler(string(f.payload)); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, conn.handleClose(f.payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, conn.fail(CloseProtocolError, errors.New("Gee: websocket new message before previous one finished"))
+			}
+			messageType = f.opcode
+			message = f.payload
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, conn.fail(CloseProtocolError, errors.New("Gee: websocket continuation without a message"))
+			}
+			message = append(message, f.payload...)
+		default:
+			return 0, nil, conn.fail(CloseProtocolError, fmt.Errorf("Gee: websocket unknown opcode %d", f.opcode))
+		}
+		if !f.fin {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, conn.fail(CloseInvalidFramePayloadData, errors.New("Gee: websocket text message is not UTF-8"))
+		}
+		return messageType, message, nil
+	}
+}
@@ -0,0 +1,245 @@
+package Gee
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wsTestClient speaks just enough RFC 6455 to drive the server in tests.
+type wsTestClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, path string, extra http.Header) (*wsTestClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for key, values := range extra {
+		req.Header[key] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("write handshake failed: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("read handshake failed: %v", err)
+	}
+	return &wsTestClient{conn: conn, br: br}, resp
+}
+
+func (cl *wsTestClient) writeFrame(fin bool, opcode int, payload []byte, masked bool) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	header := []byte{b0, 0}
+	switch {
+	case len(payload) <= 125:
+		header[1] = byte(len(payload))
+	default:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	}
+	data := append([]byte(nil), payload...)
+	if masked {
+		header[1] |= 0x80
+		mask := []byte{1, 2, 3, 4}
+		header = append(header, mask...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+	_, _ = cl.conn.Write(append(header, data...))
+}
+
+func (cl *wsTestClient) readFrame(t *testing.T) (int, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(cl.br, head[:]); err != nil {
+		t.Fatalf("read frame failed: %v", err)
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+	length := int(head[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(cl.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, _ = io.ReadFull(cl.br, payload)
+	return int(head[0] & 0x0f), payload
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	engine := New()
+	engine.WS("/echo/:room", func(c *Context, conn *Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(messageType, append([]byte(c.Param("room")+":"), data...))
+		}
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	cl, resp := dialWS(t, srv, "/echo/lobby", nil)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %s", got)
+	}
+
+	cl.writeFrame(true, TextMessage, []byte("hello"), true)
+	if op, payload := cl.readFrame(t); op != TextMessage || string(payload) != "lobby:hello" {
+		t.Fatalf("unexpected echo %d %q", op, payload)
+	}
+
+	cl.writeFrame(false, BinaryMessage, []byte("frag"), true)
+	cl.writeFrame(true, PingMessage, []byte("p"), true)
+	cl.writeFrame(true, continuationFrame, []byte(strings.Repeat("x", 200)), true)
+	if op, payload := cl.readFrame(t); op != PongMessage || string(payload) != "p" {
+		t.Fatalf("expected pong between fragments, got %d %q", op, payload)
+	}
+	if op, payload := cl.readFrame(t); op != BinaryMessage || string(payload) != "lobby:frag"+strings.Repeat("x", 200) {
+		t.Fatalf("unexpected reassembled message %d len=%d", op, len(payload))
+	}
+
+	cl.writeFrame(true, CloseMessage, []byte{0x03, 0xe8}, true)
+	if op, payload := cl.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(payload) != CloseNormalClosure {
+		t.Fatalf("expected close echo, got %d %v", op, payload)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	closeErrs := make(chan error, 2)
+	upgrader := &Upgrader{ReadLimit: 8}
+	engine := New()
+	engine.GET("/ws", func(c *Context) {
+		conn, err := upgrader.Upgrade(c)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		closeErrs <- err
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	cl, _ := dialWS(t, srv, "/ws", nil)
+	cl.writeFrame(true, TextMessage, []byte("hi"), false)
+	if op, payload := cl.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(payload) != CloseProtocolError {
+		t.Fatalf("expected protocol error close for unmasked frame, got %d %v", op, payload)
+	}
+
+	cl, _ = dialWS(t, srv, "/ws", nil)
+	cl.writeFrame(true, TextMessage, []byte("0123456789"), true)
+	if op, payload := cl.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Fatalf("expected message too big close, got %d %v", op, payload)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-closeErrs; err == nil {
+			t.Fatal("expected ReadMessage to fail")
+		}
+	}
+}
+
+func TestWebSocketDefaultReadLimit(t *testing.T) {
+	engine := New()
+	engine.WS("/ws", func(c *Context, conn *Conn) {
+		_, _, _ = conn.ReadMessage()
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	// A forged header declaring a 4 EiB payload must be refused before any
+	// buffer is sized for it.
+	cl, _ := dialWS(t, srv, "/ws", nil)
+	header := []byte{0x80 | TextMessage, 0x80 | 127}
+	header = binary.BigEndian.AppendUint64(header, 1<<62)
+	_, _ = cl.conn.Write(append(header, 1, 2, 3, 4))
+	if op, payload := cl.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Fatalf("expected message too big close, got %d %v", op, payload)
+	}
+}
+
+func TestWebSocketHandshakeRejected(t *testing.T) {
+	engine := New()
+	engine.WS("/ws", func(c *Context, conn *Conn) {})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	_, resp := dialWS(t, srv, "/ws", http.Header{"Origin": {"http://evil.example"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for cross origin, got %d", resp.StatusCode)
+	}
+	_, resp = dialWS(t, srv, "/ws", http.Header{"Sec-Websocket-Version": {"8"}})
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Fatalf("expected 426 with supported version, got %d", resp.StatusCode)
+	}
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatalf("plain request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for plain GET, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebSocketConcurrentWrites(t *testing.T) {
+	engine := New()
+	engine.WS("/ws", func(c *Context, conn *Conn) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = conn.WriteText(strings.Repeat("z", 300))
+			}()
+		}
+		wg.Wait()
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway {
+			t.Errorf("expected going-away close, got %v", err)
+		}
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	cl, _ := dialWS(t, srv, "/ws", nil)
+	for i := 0; i < 10; i++ {
+		if op, payload := cl.readFrame(t); op != TextMessage || len(payload) != 300 {
+			t.Fatalf("interleaved frame %d: op=%d len=%d", i, op, len(payload))
+		}
+	}
+	cl.writeFrame(true, CloseMessage, []byte{0x03, 0xe9, 'b', 'y', 'e'}, true)
+	if op, _ := cl.readFrame(t); op != CloseMessage {
+		t.Fatalf("expected close reply, got %d", op)
+	}
+}
@@ -6,6 +6,29 @@ import (
 	"testing"
 )
 
+// discardWriter is a reusable ResponseWriter so benchmarks only measure the
+// engine itself.
+type discardWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(code int)        { w.code = code }
+
+func newStaticRouteEngine() *Engine {
+	engine := New()
+	engine.Use(func(c *Context) { c.Next() })
+	engine.GET("/healthz", func(c *Context) {
+		c.Status(http.StatusOK)
+	})
+	engine.GET("/users/:id", func(c *Context) {
+		c.Status(http.StatusOK)
+	})
+	return engine
+}
+
 func BenchmarkEngineHealthz(b *testing.B) {
 	engine := New()
 	engine.GET("/healthz", func(c *Context) {
@@ -22,3 +45,25 @@ func BenchmarkEngineHealthz(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkEngineStaticRoute(b *testing.B) {
+	engine := newStaticRouteEngine()
+	w := &discardWriter{header: make(http.Header)}
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkEngineParamRoute(b *testing.B) {
+	engine := newStaticRouteEngine()
+	w := &discardWriter{header: make(http.Header)}
+	req, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(w, req)
+	}
+}
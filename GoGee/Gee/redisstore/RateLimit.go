@@ -0,0 +1,134 @@
+// Package redisstore backs Gee stores with Redis so state is shared between
+// processes.
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"GoGee/Gee"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Both scripts read the clock with TIME so every process agrees on it, and
+// return {allowed, remaining, retry_ms, reset_ms}.
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+if tokens == nil then
+	tokens = capacity
+else
+	tokens = math.min(capacity, tokens + math.max(0, now - tonumber(state[2])) * rate)
+end
+local allowed, retry = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local start = now - now % window
+local state = redis.call("HMGET", KEYS[1], "start", "curr", "prev")
+local curr = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+local last = tonumber(state[1])
+if last ~= start then
+	if last == start - window then
+		prev = curr
+	else
+		prev = 0
+	end
+	curr = 0
+end
+local elapsed = now - start
+local estimate = prev * (window - elapsed) / window + curr
+local allowed, remaining, retry = 0, 0, 0
+if estimate + 1 <= limit then
+	curr = curr + 1
+	allowed = 1
+	remaining = math.floor(limit - estimate - 1)
+elseif prev > 0 and curr + 1 <= limit then
+	retry = math.ceil(window * (1 - (limit - curr - 1) / prev) - elapsed)
+else
+	retry = window - elapsed
+end
+local reset = 0
+if curr > 0 then
+	reset = 2 * window - elapsed
+elseif prev > 0 then
+	reset = window - elapsed
+end
+redis.call("HSET", KEYS[1], "start", start, "curr", curr, "prev", prev)
+redis.call("PEXPIRE", KEYS[1], start + 2 * window - now)
+return {allowed, remaining, retry, reset}
+`)
+
+// RateLimitStore implements Gee.RateLimitStore with one Redis hash per key,
+// updated atomically by Lua scripts. Keys expire once their quota has
+// recovered, so idle clients cost nothing.
+type RateLimitStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRateLimitStore(client redis.UniversalClient, prefix string) *RateLimitStore {
+	if client == nil {
+		panic("redisstore: redis client is nil")
+	}
+	return &RateLimitStore{client: client, prefix: prefix}
+}
+
+func (s *RateLimitStore) Take(ctx context.Context, key string, rule Gee.RateLimitRule) (Gee.RateLimitResult, error) {
+	window := rule.Window.Milliseconds()
+	if window <= 0 {
+		return Gee.RateLimitResult{}, fmt.Errorf("redisstore: rate limit window %s is shorter than a millisecond", rule.Window)
+	}
+	var (
+		limit = rule.Limit
+		reply []int64
+		err   error
+	)
+	keys := []string{s.prefix + key}
+	switch rule.Algorithm {
+	case Gee.SlidingWindow:
+		reply, err = slidingWindowScript.Run(ctx, s.client, keys, rule.Limit, window).Int64Slice()
+	default:
+		if rule.Burst > 0 {
+			limit = rule.Burst
+		}
+		rate := float64(rule.Limit) / float64(window)
+		reply, err = tokenBucketScript.Run(ctx, s.client, keys, limit, rate).Int64Slice()
+	}
+	if err != nil {
+		return Gee.RateLimitResult{}, err
+	}
+	if len(reply) != 4 {
+		return Gee.RateLimitResult{}, fmt.Errorf("redisstore: unexpected rate limit reply %v", reply)
+	}
+	return Gee.RateLimitResult{
+		Allowed:    reply[0] == 1,
+		Limit:      limit,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+		Reset:      time.Duration(reply[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package redisstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"GoGee/Gee"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newStore(t *testing.T) (*RateLimitStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Unix(1700000000, 0))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRateLimitStore(client, "test:"), mr
+}
+
+func TestTokenBucket(t *testing.T) {
+	store, mr := newStore(t)
+	ctx := context.Background()
+	rule := Gee.RateLimitRule{Limit: 2, Window: time.Second}
+
+	for i, remaining := range []int{1, 0} {
+		r, err := store.Take(ctx, "ip:1", rule)
+		if err != nil {
+			t.Fatalf("take failed: %v", err)
+		}
+		if !r.Allowed || r.Remaining != remaining || r.Limit != 2 {
+			t.Fatalf("request %d: unexpected result %+v", i, r)
+		}
+	}
+	r, err := store.Take(ctx, "ip:1", rule)
+	if err != nil {
+		t.Fatalf("take failed: %v", err)
+	}
+	if r.Allowed || r.RetryAfter != 500*time.Millisecond || r.Reset != time.Second {
+		t.Fatalf("expected rejection, got %+v", r)
+	}
+	if ttl := mr.TTL("test:ip:1"); ttl != time.Second {
+		t.Fatalf("expected key to expire once refilled, ttl %s", ttl)
+	}
+
+	mr.SetTime(time.Unix(1700000000, 0).Add(500 * time.Millisecond))
+	if r, _ := store.Take(ctx, "ip:1", rule); !r.Allowed {
+		t.Fatalf("expected a refilled token, got %+v", r)
+	}
+	if r, _ := store.Take(ctx, "ip:2", rule); !r.Allowed || r.Remaining != 1 {
+		t.Fatalf("keys must not share a bucket, got %+v", r)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	store, mr := newStore(t)
+	ctx := context.Background()
+	rule := Gee.RateLimitRule{Algorithm: Gee.SlidingWindow, Limit: 4, Window: 10 * time.Second}
+
+	for i := 0; i < 4; i++ {
+		if r, err := store.Take(ctx, "k", rule); err != nil || !r.Allowed || r.Remaining != 3-i {
+			t.Fatalf("request %d: %+v %v", i, r, err)
+		}
+	}
+	if r, _ := store.Take(ctx, "k", rule); r.Allowed || r.RetryAfter != 10*time.Second || r.Reset != 20*time.Second {
+		t.Fatalf("expected rejection until the window ends, got %+v", r)
+	}
+
+	mr.SetTime(time.Unix(1700000015, 0))
+	for i := 0; i < 2; i++ {
+		if r, _ := store.Take(ctx, "k", rule); !r.Allowed {
+			t.Fatalf("request %d in next window rejected: %+v", i, r)
+		}
+	}
+	if r, _ := store.Take(ctx, "k", rule); r.Allowed || r.RetryAfter != 2500*time.Millisecond {
+		t.Fatalf("expected rejection until the previous window decays, got %+v", r)
+	}
+}
+
+func TestMiddlewareWithRedisStore(t *testing.T) {
+	store, _ := newStore(t)
+	engine := Gee.New()
+	engine.Use(Gee.RateLimit(Gee.RateLimitConfig{Limit: 1, Window: time.Minute, Store: store}))
+	engine.GET("/ping", func(c *Gee.Context) { c.String(http.StatusOK, "pong") })
+
+	if code := serve(engine); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := serve(engine); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", code)
+	}
+}
+
+func serve(engine *Gee.Engine) int {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec.Code
+}
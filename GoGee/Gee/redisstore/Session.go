@@ -0,0 +1,41 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"GoGee/Gee"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SessionBackend implements Gee.SessionBackend with one Redis string per
+// session, expiring with the session.
+type SessionBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+var _ Gee.SessionBackend = (*SessionBackend)(nil)
+
+func NewSessionBackend(client redis.UniversalClient, prefix string) *SessionBackend {
+	if client == nil {
+		panic("redisstore: redis client is nil")
+	}
+	return &SessionBackend{client: client, prefix: prefix}
+}
+
+func (b *SessionBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := b.client.Get(ctx, b.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+func (b *SessionBackend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, b.prefix+id, data, ttl).Err()
+}
+func (b *SessionBackend) Delete(ctx context.Context, id string) error {
+	return b.client.Del(ctx, b.prefix+id).Err()
+}
@@ -0,0 +1,38 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSessionBackend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	backend := NewSessionBackend(client, "sess:")
+	ctx := context.Background()
+
+	if data, err := backend.Load(ctx, "missing"); data != nil || err != nil {
+		t.Fatalf("expected nothing for unknown ids, got %q %v", data, err)
+	}
+	if err := backend.Save(ctx, "abc", []byte(`{"user":"alice"}`), time.Minute); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if ttl := mr.TTL("sess:abc"); ttl != time.Minute {
+		t.Fatalf("expected session to expire with its ttl, got %s", ttl)
+	}
+	if data, err := backend.Load(ctx, "abc"); string(data) != `{"user":"alice"}` || err != nil {
+		t.Fatalf("unexpected load %q %v", data, err)
+	}
+	if err := backend.Delete(ctx, "abc"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	mr.FastForward(time.Second)
+	if data, _ := backend.Load(ctx, "abc"); data != nil {
+		t.Fatalf("expected deleted session to be gone, got %q", data)
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"GoGee/Gee"
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -11,12 +15,14 @@ func traceByGroup(group string) Gee.HandlerFunc {
 	return func(c *Gee.Context) {
 		t := time.Now()
 		c.Next()
-		log.Printf("[%d] %s in %v for group %s", c.StatusCode, c.Rep.RequestURI, time.Since(t), group)
+		log.Printf("[%d] %s in %v for group %s", c.Writer.Status(), c.Rep.RequestURI, time.Since(t), group)
 	}
 }
 
 func main() {
 	r := Gee.Default()
+	r.ReadHeaderTimeout = 5 * time.Second
+	r.IdleTimeout = time.Minute
 	r.Use(Gee.RequestID())
 	r.NoRoute(func(c *Gee.Context) {
 		c.JSON(http.StatusNotFound, Gee.H{
@@ -90,5 +96,20 @@ func main() {
 		c.JSON(http.StatusOK, Gee.H{"routes": r.Routes()})
 	})
 
-	r.Run(":9999")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := r.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+	if err := r.Run(":9999"); err != nil {
+		log.Fatal(err)
+	}
+	<-drained
 }